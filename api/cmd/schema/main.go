@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+
+	"github.com/style77/stockfish-or-not/internal/protocol"
+)
+
+func main() {
+	output := flag.String("o", "", "file to write the schema to, stdout if empty")
+	flag.Parse()
+
+	schema, err := json.MarshalIndent(protocol.Schema(), "", "  ")
+	if err != nil {
+		log.Fatal("Error encoding schema:", err)
+	}
+	schema = append(schema, '\n')
+
+	if *output == "" {
+		os.Stdout.Write(schema)
+		return
+	}
+
+	if err := os.WriteFile(*output, schema, 0o644); err != nil {
+		log.Fatal("Error writing schema:", err)
+	}
+}
@@ -1,20 +1,51 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/style77/stockfish-or-not/internal"
+	"github.com/style77/stockfish-or-not/internal/config"
+	"github.com/style77/stockfish-or-not/internal/games"
+	"github.com/style77/stockfish-or-not/internal/random"
+	"github.com/style77/stockfish-or-not/internal/store"
 	"github.com/style77/stockfish-or-not/internal/ws"
 )
 
 func main() {
-	app := internal.CreateApp()
+	configPath := flag.String("config", os.Getenv("SFON_CONFIG"), "path to a JSON config file (env SFON_CONFIG)")
+	flags := config.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath, flags)
+	if err != nil {
+		log.Fatal("Error loading config:", err)
+	}
+
+	gameStore, err := store.NewFileStore(cfg.ArchivePath)
+	if err != nil {
+		log.Fatal("Error opening game archive:", err)
+	}
+	defer gameStore.Close()
+
+	engines, err := internal.NewEngineRegistry(cfg.AI, random.Default())
+	if err != nil {
+		log.Fatal("Error setting up AI engines:", err)
+	}
+	defer engines.Close()
+
+	app := internal.CreateApp(cfg, gameStore, engines)
 
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
 		ws.HandleConnections(w, r, app)
 	})
 
-	log.Println("Server started on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	http.HandleFunc("GET /games/{roomID}/pgn", func(w http.ResponseWriter, r *http.Request) {
+		games.HandlePGN(w, r, app)
+	})
+
+	log.Println("Server started on", cfg.Addr)
+	log.Fatal(http.ListenAndServe(cfg.Addr, nil))
 }
@@ -3,8 +3,7 @@ module github.com/style77/stockfish-or-not
 go 1.22.1
 
 require (
-	github.com/freeeve/uci v1.0.0 // indirect
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/gorilla/websocket v1.5.3 // indirect
-	github.com/notnil/chess v1.9.0 // indirect
+	github.com/google/uuid v1.6.0
+	github.com/gorilla/websocket v1.5.3
+	github.com/notnil/chess v1.9.0
 )
@@ -2,33 +2,70 @@ package internal
 
 import (
 	"log"
-	"math/rand/v2"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/notnil/chess"
-	"github.com/style77/stockfish-or-not/internal/constants"
+	"github.com/style77/stockfish-or-not/internal/clock"
+	"github.com/style77/stockfish-or-not/internal/config"
 	"github.com/style77/stockfish-or-not/internal/engine"
 	"github.com/style77/stockfish-or-not/internal/game"
 	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/random"
+	"github.com/style77/stockfish-or-not/internal/store"
 	"github.com/style77/stockfish-or-not/internal/timer"
 	"github.com/style77/stockfish-or-not/internal/utils"
 )
 
 type App struct {
+	Config         *config.Config
 	WaitingPlayers []*models.Player
 	Rooms          map[string]*models.Room
-	mux            sync.Mutex
+	Sessions       map[string]*models.Player // session token -> player
+	Store          store.GameStore
+	Engines        *engine.Registry
+	// Clock and Rand drive every wait and random choice of the app, tests
+	// swap them for a fake clock and a seeded source before serving
+	Clock clock.Clock
+	Rand  random.Rand
+	mux   sync.Mutex
 }
 
-func CreateApp() *App {
+func CreateApp(cfg *config.Config, gameStore store.GameStore, engines *engine.Registry) *App {
 	return &App{
+		Config:         cfg,
 		WaitingPlayers: make([]*models.Player, 0),
 		Rooms:          make(map[string]*models.Room),
+		Sessions:       make(map[string]*models.Player),
+		Store:          gameStore,
+		Engines:        engines,
+		Clock:          clock.Real{},
+		Rand:           random.Default(),
 	}
 }
 
+// NewEngineRegistry sets up the configured engines and personas, engine
+// processes are only started by the first game that needs them. rnd is the
+// random source of the built-in engine.
+func NewEngineRegistry(cfg config.AIConfig, rnd random.Rand) (*engine.Registry, error) {
+	engines := engine.NewRegistry()
+
+	for _, ec := range cfg.EngineConfigs() {
+		engines.AddEngine(ec.Name, engine.OpenEngine(ec.Path, ec.PoolSize, ec.Options, rnd))
+	}
+
+	for _, persona := range cfg.PersonaList() {
+		if err := engines.AddPersona(persona); err != nil {
+			engines.Close()
+			return nil, err
+		}
+	}
+
+	return engines, nil
+}
+
 func setRoomTurn(room *models.Room, player1Color string, player1, player2 *models.Player) {
 	if player1Color == "black" {
 		room.Turn = player2
@@ -40,18 +77,31 @@ func setRoomTurn(room *models.Room, player1Color string, player1, player2 *model
 func (app *App) createRoom(player1, player2 *models.Player, isAI bool) *models.Room {
 	roomID := uuid.New().String()
 
+	board := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
+
 	room := &models.Room{
-		ID:        roomID,
-		Player1:   player1,
-		Player2:   player2,
-		IsAI:      isAI,
-		Moves:     make([]string, 0),
-		GameEnded: false,
+		ID:          roomID,
+		Player1:     player1,
+		Player2:     player2,
+		IsAI:        isAI,
+		Moves:       make([]string, 0),
+		Board:       board,
+		FEN:         board.FEN(),
+		RootFEN:     board.FEN(),
+		TimeControl: app.pickTimeControl(),
+		Clock:       app.Clock,
+		State:       models.RoomMatching,
+		Events:      make(chan interface{}),
+		Done:        make(chan struct{}),
+		StartedAt:   app.Clock.Now(),
 	}
 
-	player1.Room = room
-	if player2 != nil {
-		player2.Room = room
+	for _, player := range []*models.Player{player1, player2} {
+		if player != nil {
+			player.Mux.Lock()
+			player.Room = room
+			player.Mux.Unlock()
+		}
 	}
 
 	app.mux.Lock()
@@ -61,8 +111,44 @@ func (app *App) createRoom(player1, player2 *models.Player, isAI bool) *models.R
 	return room
 }
 
-func getPlayerColor() string {
-	if rand.Float64() < 0.5 {
+// endGame finishes the game in the room and forgets the room. Players keep
+// their reference to the room, so they can still submit guesses until the
+// guess window closes.
+func (app *App) endGame(player *models.Player, room *models.Room, reason string, result *utils.GameResult) {
+	if !game.HandleGameEnd(player, room, reason, result) {
+		return
+	}
+
+	app.mux.Lock()
+	delete(app.Rooms, room.ID)
+	app.mux.Unlock()
+
+	app.removeSessions(room)
+
+	// players that never submit a guess are disconnected once the window is over
+	app.Clock.AfterFunc(app.Config.Game.GuessTimeout.Duration, func() {
+		post(room, closeEvent{})
+	})
+}
+
+func (app *App) archiveGame(room *models.Room) {
+	if err := app.Store.Save(game.NewGameRecord(room)); err != nil {
+		log.Println("Error archiving game", room.ID+":", err)
+	}
+}
+
+func (app *App) pickTimeControl() timer.TimeControl {
+	controls := app.Config.Game.Controls()
+	return controls[app.Rand.IntN(len(controls))]
+}
+
+// randomDuration returns a random duration between from and to inclusive.
+func (app *App) randomDuration(from, to time.Duration) time.Duration {
+	return from + time.Duration(app.Rand.Int64N(int64(to-from)+1))
+}
+
+func (app *App) pickPlayerColor() string {
+	if app.Rand.Float64() < 0.5 {
 		return "black"
 	}
 	return "white"
@@ -75,107 +161,102 @@ func getOpponentColor(playerColor string) string {
 	return "white"
 }
 
-func notifyPlayersAboutTime(room *models.Room, color string, remainingTime int) {
-	err := utils.NotifyBothPlayers(room, map[string]interface{}{
-		"message": "Time left for " + color,
-		"roomID":  room.ID,
-		"state":   80,
-		"data": map[string]interface{}{
-			"time":  remainingTime,
-			"color": color,
-		},
-	})
+func notifyPlayersAboutTime(room *models.Room, color string, remainingTime time.Duration) {
+	err := utils.NotifyBothPlayers(room, protocol.NewEnvelope(protocol.StateTimeLeft, room.ID, "Time left for "+color, &protocol.TimeLeftData{
+		Time:         int(remainingTime.Seconds()),
+		Milliseconds: remainingTime.Milliseconds(),
+		Color:        color,
+	}))
 
 	if err != nil {
 		log.Println("Error notifying players about time:", err)
 	}
 }
 
+// newPlayerTimer creates the player's clock, its ticks and flag fall are
+// handled by the room's event loop.
+func (app *App) newPlayerTimer(room *models.Room, player *models.Player) *timer.Timer {
+	onTick := func(remainingTime time.Duration) {
+		post(room, tickEvent{player: player, remaining: remainingTime})
+	}
+
+	onFlag := func() {
+		post(room, flagEvent{player: player})
+	}
+
+	return timer.NewTimer(app.Clock, room.TimeControl, app.Config.Game.TickInterval.Duration, onTick, onFlag)
+}
+
+// flag ends the game once the player's clock ran out, the opponent wins
+// unless they can't mate anymore.
+func (app *App) flag(room *models.Room, player *models.Player) {
+	if room.State != models.RoomPlaying {
+		return
+	}
+
+	color := *player.Color
+	notifyPlayersAboutTime(room, color, 0)
+
+	result := game.TimeoutResult(room, color)
+	app.endGame(player, room, result.OutcomeReason, result)
+}
+
 func (app *App) HandleAIOpponent(player *models.Player) {
-	selectedEngine := "stockfish"
+	if isDisconnected(player) {
+		log.Println("Player left before being matched with an AI opponent")
+		return
+	}
 
-	manager, elo := engine.DeterminateAI()
+	manager, persona := app.Engines.NewAI(app.Config.AI.MultiPV, app.Rand)
+	strength := manager.Strength()
+	elo := strength.Elo
+	selectedEngine := persona.Engine
 
 	aiOpponent := &models.Player{IsAI: true, Rank: &elo, Engine: &selectedEngine, AI: manager}
 	room := app.createRoom(player, aiOpponent, true)
 
-	playerColor := getPlayerColor()
+	playerColor := app.pickPlayerColor()
 	opponentColor := getOpponentColor(playerColor)
 
 	player.Color = &playerColor
 	aiOpponent.Color = &opponentColor
 
-	aiOpponent.Timer = timer.NewTimer(constants.GameTime, func(remainingTime int) {
-		notifyPlayersAboutTime(room, opponentColor, remainingTime)
-
-		if remainingTime == 0 {
-			var outcome chess.Outcome
-
-			// aiOpponent timer is up, so player wins
-			if playerColor == "white" {
-				outcome = chess.WhiteWon
-			} else {
-				outcome = chess.BlackWon
-			}
-
-			game.HandleGameEnd(aiOpponent, room, "Time is up", &utils.GameResult{
-				Outcome:       outcome,
-				OutcomeReason: "Time is up",
-			})
-		}
-	})
-	player.Timer = timer.NewTimer(constants.GameTime, func(remainingTime int) {
-		notifyPlayersAboutTime(room, playerColor, remainingTime)
-
-		if remainingTime == 0 {
-			var outcome chess.Outcome
+	aiOpponent.Timer = app.newPlayerTimer(room, aiOpponent)
+	player.Timer = app.newPlayerTimer(room, player)
 
-			// player timer is up, so aiOpponent wins
-			if playerColor == "white" {
-				outcome = chess.BlackWon
-			} else {
-				outcome = chess.WhiteWon
-			}
+	setRoomTurn(room, playerColor, player, aiOpponent)
 
-			game.HandleGameEnd(player, room, "Time is up", &utils.GameResult{
-				Outcome:       outcome,
-				OutcomeReason: "Time is up",
-			})
-		}
-	})
+	log.Println("Player", player.Conn.RemoteAddr(), "has been matched with AI persona", persona.Name, "with Elo", elo, "("+string(strength.Mode)+" at", strength.EngineElo, "Elo)")
+	app.notifyMatched(room, player)
 
-	setRoomTurn(room, playerColor, player, aiOpponent)
+	app.startRoom(room)
+}
 
-	log.Println("Player", player.Conn.RemoteAddr(), "has been matched with an AI opponent with Elo", elo)
-	player.Conn.WriteJSON(map[string]interface{}{
-		"message": "You have been matched with an opponent! You are playing as " + playerColor,
-		"roomID":  room.ID,
-		"state":   1,
-		"data": map[string]interface{}{
-			"color":    playerColor,
-			"gameTime": constants.GameTime, // seconds
-		},
-	})
+// notifyMatched tells a human player their color and time control. It reads
+// the same for AI and human opponents, so it gives nothing away.
+func (app *App) notifyMatched(room *models.Room, player *models.Player) {
+	color := *player.Color
 
-	// if player is black, AI makes the first move
-	if playerColor == "black" {
-		move, err := manager.ProcessMove("", constants.MaxDepth)
-		if err != nil {
-			log.Println("Error processing move for AI opponent:", err)
-			return
-		}
+	err := utils.SafelyNotifyPlayer(player, protocol.NewEnvelope(protocol.StateMatched, room.ID, "You have been matched with an opponent! You are playing as "+color, &protocol.MatchedData{
+		Color:        color,
+		GameTime:     int(room.TimeControl.Initial().Seconds()),
+		TimeControl:  game.DescribeTimeControl(room.TimeControl),
+		SessionToken: app.issueSession(player),
+	}))
 
-		app.ProcessMove(aiOpponent, move, true)
+	if err != nil {
+		log.Println("Error notifying player about match:", err)
 	}
 }
 
 func (app *App) FindOpponent(player *models.Player) {
-	isOpponentAi := rand.Float64() < constants.AIPlayerPosibility
+	matchmaking := app.Config.Matchmaking
+	isOpponentAi := app.Rand.Float64() < matchmaking.AIProbability
 
 	if isOpponentAi {
-		waitingTime := rand.IntN(constants.PlayerLookingIntervalRangeTo-constants.PlayerLookingIntervalRangeFrom) + constants.PlayerLookingIntervalRangeFrom
+		waitingTime := app.randomDuration(matchmaking.LookingIntervalFrom.Duration, matchmaking.LookingIntervalTo.Duration)
 
-		time.AfterFunc(time.Duration(waitingTime)*time.Second, func() {
+		app.Clock.AfterFunc(waitingTime, func() {
 			app.HandleAIOpponent(player)
 		})
 	} else {
@@ -183,53 +264,63 @@ func (app *App) FindOpponent(player *models.Player) {
 	}
 }
 
-func getRandomPlayerThatsNot(player *models.Player, app *App) *models.Player {
+// claimOpponent takes player and a waiting opponent off the waiting list in
+// one go, or puts player on the list when nobody else is waiting. claimed
+// reports that player was listed but another player already took them off
+// the list for their own game.
+func claimOpponent(player *models.Player, app *App, listed bool) (opponent *models.Player, claimed bool) {
 	app.mux.Lock()
 	defer app.mux.Unlock()
 
-	if len(app.WaitingPlayers) == 0 {
-		return nil
+	if listed && !isListed(player, app) {
+		return nil, true
 	}
 
+	log.Println("Players waiting:", len(app.WaitingPlayers))
+
 	for _, p := range app.WaitingPlayers {
-		if p != player {
-			return p
+		if p != player && !isDisconnected(p) {
+			opponent = p
+			break
 		}
 	}
 
-	return nil
-}
-
-func removePlayerFromWaitingList(player *models.Player, app *App) {
-	app.mux.Lock()
-	defer app.mux.Unlock()
-
-	for i, p := range app.WaitingPlayers {
-		if p == player {
-			app.WaitingPlayers = append(app.WaitingPlayers[:i], app.WaitingPlayers[i+1:]...)
-			break
+	if opponent == nil {
+		if !listed {
+			app.WaitingPlayers = append(app.WaitingPlayers, player)
 		}
+		return nil, false
 	}
+
+	unlist(player, app)
+	unlist(opponent, app)
+	return opponent, false
 }
 
-func isPlayerInWaitingList(player *models.Player, app *App) bool {
+// removePlayerFromWaitingList reports whether player was still waiting.
+func removePlayerFromWaitingList(player *models.Player, app *App) bool {
 	app.mux.Lock()
 	defer app.mux.Unlock()
 
-	for _, p := range app.WaitingPlayers {
+	return unlist(player, app)
+}
+
+// unlist is removePlayerFromWaitingList for callers holding the app lock.
+func unlist(player *models.Player, app *App) bool {
+	for i, p := range app.WaitingPlayers {
 		if p == player {
+			app.WaitingPlayers = append(app.WaitingPlayers[:i], app.WaitingPlayers[i+1:]...)
 			return true
 		}
 	}
 	return false
 }
 
-func isPlayerInGame(player *models.Player, app *App) bool {
-	app.mux.Lock()
-	defer app.mux.Unlock()
-
-	for _, room := range app.Rooms {
-		if room.Player1 == player || room.Player2 == player {
+// isListed reports whether player is on the waiting list, the caller holds
+// the app lock.
+func isListed(player *models.Player, app *App) bool {
+	for _, p := range app.WaitingPlayers {
+		if p == player {
 			return true
 		}
 	}
@@ -237,25 +328,26 @@ func isPlayerInGame(player *models.Player, app *App) bool {
 }
 
 func waitForRealPlayer(player *models.Player, app *App) {
-	timeout := time.After(constants.TimeoutForOpponent * time.Second)
-	ticker := time.NewTicker(1 * time.Second)
+	timeout := app.Clock.After(app.Config.Matchmaking.OpponentTimeout.Duration)
+	ticker := app.Clock.NewTicker(1 * time.Second)
 	defer ticker.Stop()
 
+	listed := false
+
 	for {
 		select {
-		case <-ticker.C:
-			if isPlayerInGame(player, app) {
-				log.Println("Player is already in a game:", player.Conn.RemoteAddr())
+		case <-ticker.C():
+			if isDisconnected(player) {
+				removePlayerFromWaitingList(player, app)
 				return
 			}
 
 			log.Println("Looking for an opponent for player:", player.Conn.RemoteAddr())
-			log.Println("Players waiting:", len(app.WaitingPlayers))
 
-			opponent := getRandomPlayerThatsNot(player, app)
-			if opponent != nil {
-				removePlayerFromWaitingList(opponent, app)
-				removePlayerFromWaitingList(player, app)
+			opponent, claimed := claimOpponent(player, app, listed)
+			if claimed {
+				log.Println("Player has been matched by another player:", player.Conn.RemoteAddr())
+				return
 			}
 
 			if opponent != nil {
@@ -263,7 +355,7 @@ func waitForRealPlayer(player *models.Player, app *App) {
 				log.Println("Player 1:", player.Conn.RemoteAddr())
 				log.Println("Player 2:", opponent.Conn.RemoteAddr())
 
-				player1Color := getPlayerColor()
+				player1Color := app.pickPlayerColor()
 				player2Color := getOpponentColor(player1Color)
 
 				player.Color = &player1Color
@@ -272,77 +364,27 @@ func waitForRealPlayer(player *models.Player, app *App) {
 				room := app.createRoom(player, opponent, false)
 				setRoomTurn(room, player1Color, player, opponent)
 
-				player.Timer = timer.NewTimer(constants.GameTime, func(remainingTime int) {
-					notifyPlayersAboutTime(room, player1Color, remainingTime)
-
-					if remainingTime == 0 {
-						var outcome chess.Outcome
-
-						// player timer is up, so opponent wins
-						if player1Color == "white" {
-							outcome = chess.BlackWon
-						} else {
-							outcome = chess.WhiteWon
-						}
-
-						game.HandleGameEnd(player, room, "Time is up", &utils.GameResult{
-							Outcome:       outcome,
-							OutcomeReason: "Time is up",
-						})
-					}
-				})
-				opponent.Timer = timer.NewTimer(constants.GameTime, func(remainingTime int) {
-					notifyPlayersAboutTime(room, player2Color, remainingTime)
-
-					if remainingTime == 0 {
-						var outcome chess.Outcome
-
-						// opponent timer is up, so player wins
-						if player1Color == "white" {
-							outcome = chess.WhiteWon
-						} else {
-							outcome = chess.BlackWon
-						}
-
-						game.HandleGameEnd(opponent, room, "Time is up", &utils.GameResult{
-							Outcome:       outcome,
-							OutcomeReason: "Time is up",
-						})
-					}
-				})
-
-				player.Conn.WriteJSON(map[string]interface{}{
-					"message": "You have been matched with a player! You are playing as " + player1Color,
-					"roomID":  room.ID,
-					"state":   1,
-					"data": map[string]interface{}{
-						"color":    player1Color,
-						"gameTime": constants.GameTime, // seconds
-					},
-				})
-				opponent.Conn.WriteJSON(map[string]interface{}{
-					"message": "You have been matched with a player! You are playing as " + player2Color,
-					"roomID":  room.ID,
-					"state":   1,
-					"data": map[string]interface{}{
-						"color":    player2Color,
-						"gameTime": constants.GameTime,
-					},
-				})
+				player.Timer = app.newPlayerTimer(room, player)
+				opponent.Timer = app.newPlayerTimer(room, opponent)
+
+				app.notifyMatched(room, player)
+				app.notifyMatched(room, opponent)
 
 				log.Println("Players matched:", player.Conn.RemoteAddr(), opponent.Conn.RemoteAddr())
+				app.startRoom(room)
 				return
 			}
 
-			log.Println("No opponent found for player:", player.Conn.RemoteAddr())
-			log.Println("Adding player to waiting list:", player.Conn.RemoteAddr())
-
-			if !isPlayerInWaitingList(player, app) {
-				app.mux.Lock()
-				app.WaitingPlayers = append(app.WaitingPlayers, player)
-				app.mux.Unlock()
+			if !listed {
+				log.Println("No opponent found, added player to waiting list:", player.Conn.RemoteAddr())
+				listed = true
 			}
 		case <-timeout:
+			// another player may have claimed this one just now
+			if !removePlayerFromWaitingList(player, app) && listed {
+				return
+			}
+
 			// Timeout: if no real player is found, match with AI
 			app.HandleAIOpponent(player)
 
@@ -351,109 +393,142 @@ func waitForRealPlayer(player *models.Player, app *App) {
 	}
 }
 
+// ProcessMove hands the player's move to their room.
 func (app *App) ProcessMove(player *models.Player, move string, isFirstMove bool) {
-	room := player.Room
+	room := playerRoom(player)
 	if room == nil {
 		log.Println("Player is not in a room.")
 		return
 	}
 
-	if room.Turn != player {
-		log.Println("Not player's turn.")
-		return
+	if !post(room, moveEvent{player: player, move: move}) {
+		log.Println("Dropped move", move, "for closed room", room.ID)
 	}
+}
+
+// playMove plays the move on the board and hands the turn to the opponent.
+func (app *App) playMove(room *models.Room, player *models.Player, move string) {
+	if moveErr := game.ApplyMove(room, player, move); moveErr != nil {
+		if moveErr == game.ErrTimeUp {
+			log.Println("Move", move, "came after the flag fell in room", room.ID)
+			if !player.IsAI {
+				game.RejectMove(player, room, move, moveErr)
+			}
+			app.flag(room, player)
+			return
+		}
 
-	opponent := room.Player1
-	if player == room.Player1 {
-		opponent = room.Player2
+		if player.IsAI {
+			log.Println("Rejected AI move", move, "in room", room.ID+":", moveErr)
+			return
+		}
+
+		log.Println("Rejected move", move, "in room", room.ID+":", moveErr)
+		game.RejectMove(player, room, move, moveErr)
+		return
 	}
 
-	err := utils.SafelyNotifyPlayer(opponent, map[string]interface{}{
-		"message": "Opponent made move",
-		"roomID":  room.ID,
-		"state":   78,
-		"data": map[string]interface{}{
-			"move": move,
-		},
-	})
+	opponent := getOpponent(room, player)
+
+	err := utils.SafelyNotifyPlayer(opponent, protocol.NewEnvelope(protocol.StateOpponentMove, room.ID, "Opponent made move", &protocol.OpponentMoveData{
+		Move:  move,
+		Clock: game.Clock(room),
+	}))
 
 	if err != nil {
 		log.Println("Error notifying opponent about move:", err)
 	}
 
-	if room.GameEnded {
-		log.Println("Game has already ended in room:", room.ID)
-		return
-	}
-
-	room.Mux.Lock()
-	room.Moves = append(room.Moves, move)
-	room.Mux.Unlock()
-
-	result, gameEnded := utils.CheckEndGameStates(room.Moves)
+	result, gameEnded := game.CheckEndGameStates(room)
 
 	if gameEnded {
-		game.HandleGameEnd(player, room, result.OutcomeReason, result)
+		app.endGame(player, room, result.OutcomeReason, result)
 		return
 	}
 
 	game.ChangeTurn(room)
 
-	if room.IsAI && opponent.AI != nil {
-		go func() {
-			processAIMove(room, opponent)
-		}()
+	if opponent.AI != nil {
+		app.thinkAIMove(room, opponent, app.Rand.IntN(app.Config.AI.MaxDepth)+1)
 	}
 }
 
-func processAIMove(room *models.Room, aiPlayer *models.Player) {
-	waitTime := rand.IntN(constants.AIMoveWaitTimeFrom-constants.AIMoveWaitTimeFrom+1) + constants.AIMoveWaitTimeFrom
-	log.Printf("AI will take %d seconds to make its move...\n", waitTime)
-	time.Sleep(time.Duration(waitTime) * time.Second)
+func (app *App) ProcessGuess(player *models.Player, guess string) {
+	room := playerRoom(player)
+	if room == nil {
+		log.Println("Player is not in a room.")
+		return
+	}
 
-	randomDepth := rand.IntN(constants.MaxDepth) + 1
-	aiMove, err := aiPlayer.AI.ProcessMove(utils.GetPosition(room.Moves), randomDepth)
-	if err != nil {
-		log.Println("Error getting AI move:", err)
+	if !post(room, guessEvent{player: player, guess: guess}) {
+		log.Println("Dropped guess", guess, "for closed room", room.ID)
+	}
+}
+
+func (app *App) guess(room *models.Room, player *models.Player, guess string) {
+	if guessErr := game.HandleGuess(player, room, guess); guessErr != nil {
+		log.Println("Rejected guess", guess, "in room", room.ID+":", guessErr)
+		game.RejectGuess(player, room, guess, guessErr)
 		return
 	}
 
-	log.Println("Processing AI move with depth", randomDepth, ":", aiMove)
+	log.Println("Player", player.Conn.RemoteAddr(), "guessed", guess, "in room", room.ID)
 
-	humanPlayer := room.Player1
-	if aiPlayer == room.Player1 {
-		humanPlayer = room.Player2
+	if game.GuessesDone(room) {
+		app.closeRoom(room)
 	}
+}
 
-	err = utils.SafelyNotifyPlayer(humanPlayer, map[string]interface{}{
-		"message": "Opponent made move",
-		"roomID":  room.ID,
-		"state":   78,
-		"data": map[string]interface{}{
-			"move": aiMove,
-		},
-	})
+// thinkAIMove searches the AI's candidate moves off the event loop, the
+// search counts towards the AI's think time.
+func (app *App) thinkAIMove(room *models.Room, aiPlayer *models.Player, depth int) {
+	start := app.Clock.Now()
+	position := game.EnginePosition(room)
 
-	if err != nil {
-		log.Println("Error notifying human player about AI move:", err)
+	go func() {
+		result, err := searchAIMove(aiPlayer, position, depth)
+		post(room, aiSearchedEvent{player: aiPlayer, result: result, err: err, depth: depth, start: start})
+	}()
+}
+
+// playAIMove picks one of the searched moves and holds it back until a human
+// would have found it. If the engine failed the game ends as if the opponent
+// had left, so the human player is not told it was an AI.
+func (app *App) playAIMove(room *models.Room, searched aiSearchedEvent) {
+	aiPlayer := searched.player
+	if room.State != models.RoomPlaying {
 		return
 	}
 
-	if room.GameEnded {
-		log.Println("Game has already ended in room:", room.ID)
+	if searched.err != nil {
+		log.Println("Error getting AI move, ending game in room", room.ID+":", searched.err)
+
+		app.endGame(aiPlayer, room, "opponent disconnected", &utils.GameResult{
+			Outcome:       utils.WinFor(getOpponentColor(*aiPlayer.Color)),
+			OutcomeReason: "opponent disconnected",
+		})
 		return
 	}
 
-	room.Mux.Lock()
-	room.Moves = append(room.Moves, aiMove)
-	room.Mux.Unlock()
+	move := aiPlayer.AI.SelectMove(game.MoveChoice(room, aiPlayer, searched.result))
 
-	result, gameEnded := utils.CheckEndGameStates(room.Moves)
+	thinkTime := aiPlayer.AI.ThinkTime(game.MoveContext(room, aiPlayer, move), searched.result)
+	log.Printf("AI will take %s to make its move with depth %d: %s\n", thinkTime, searched.depth, move)
 
-	if gameEnded {
-		game.HandleGameEnd(aiPlayer, room, result.OutcomeReason, result)
-		return
+	app.Clock.AfterFunc(thinkTime-app.Clock.Since(searched.start), func() {
+		post(room, moveEvent{player: aiPlayer, move: move})
+	})
+}
+
+// searchAIMove asks the engine for a move, retrying once since a crashed
+// engine is replaced by the pool.
+func searchAIMove(aiPlayer *models.Player, position engine.Position, depth int) (*engine.SearchResult, error) {
+	result, err := aiPlayer.AI.ProcessMove(position, depth)
+	if err == nil {
+		return result, nil
 	}
 
-	game.ChangeTurn(room)
+	log.Println("Error getting AI move, retrying:", err)
+
+	return aiPlayer.AI.ProcessMove(position, depth)
 }
@@ -0,0 +1,161 @@
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Fake is a clock that only moves when Advance is called. Timers, sleeps and
+// tickers that come due fire in order of their deadlines.
+type Fake struct {
+	now     time.Time
+	waiters []*waiter
+	mux     sync.Mutex
+	added   *sync.Cond // signalled whenever a waiter is added
+}
+
+type waiter struct {
+	clock  *Fake
+	at     time.Time
+	period time.Duration // non-zero for tickers
+	fire   func(now time.Time)
+}
+
+func NewFake(now time.Time) *Fake {
+	f := &Fake{now: now}
+	f.added = sync.NewCond(&f.mux)
+	return f
+}
+
+func (f *Fake) Now() time.Time {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	return f.now
+}
+
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+func (f *Fake) Sleep(d time.Duration) {
+	<-f.After(d)
+}
+
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	c := make(chan time.Time, 1)
+	f.add(d, 0, func(now time.Time) { c <- now })
+	return c
+}
+
+func (f *Fake) AfterFunc(d time.Duration, fn func()) Timer {
+	return f.add(d, 0, func(time.Time) { go fn() })
+}
+
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	c := make(chan time.Time, 1)
+	w := f.add(d, d, func(now time.Time) {
+		// like time.Ticker, ticks are dropped for slow receivers
+		select {
+		case c <- now:
+		default:
+		}
+	})
+	return fakeTicker{waiter: w, c: c}
+}
+
+// Advance moves the clock forward by d, firing everything that comes due on
+// the way.
+func (f *Fake) Advance(d time.Duration) {
+	f.mux.Lock()
+	target := f.now.Add(d)
+
+	for {
+		w := f.next(target)
+		if w == nil {
+			break
+		}
+
+		f.now = w.at
+		if w.period > 0 {
+			w.at = w.at.Add(w.period)
+		} else {
+			f.remove(w)
+		}
+		now := f.now
+
+		f.mux.Unlock()
+		w.fire(now)
+		f.mux.Lock()
+	}
+
+	f.now = target
+	f.mux.Unlock()
+}
+
+// BlockUntil waits until at least n timers, sleeps or tickers are pending,
+// so a test knows the code under test got to schedule them.
+func (f *Fake) BlockUntil(n int) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	for len(f.waiters) < n {
+		f.added.Wait()
+	}
+}
+
+func (f *Fake) add(d, period time.Duration, fire func(time.Time)) *waiter {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+
+	w := &waiter{clock: f, at: f.now.Add(d), period: period, fire: fire}
+	f.waiters = append(f.waiters, w)
+	f.added.Broadcast()
+
+	if d <= 0 && period == 0 {
+		f.remove(w)
+		fire(f.now)
+	}
+
+	return w
+}
+
+// next returns the earliest waiter due by target, the caller holds the lock.
+func (f *Fake) next(target time.Time) *waiter {
+	sort.SliceStable(f.waiters, func(i, j int) bool {
+		return f.waiters[i].at.Before(f.waiters[j].at)
+	})
+
+	if len(f.waiters) == 0 || f.waiters[0].at.After(target) {
+		return nil
+	}
+	return f.waiters[0]
+}
+
+// remove drops the waiter and reports whether it was pending, the caller
+// holds the lock.
+func (f *Fake) remove(w *waiter) bool {
+	for i, pending := range f.waiters {
+		if pending == w {
+			f.waiters = append(f.waiters[:i], f.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (w *waiter) Stop() bool {
+	w.clock.mux.Lock()
+	defer w.clock.mux.Unlock()
+
+	return w.clock.remove(w)
+}
+
+type fakeTicker struct {
+	*waiter
+	c chan time.Time
+}
+
+func (t fakeTicker) C() <-chan time.Time { return t.c }
+func (t fakeTicker) Stop()               { t.waiter.Stop() }
@@ -0,0 +1,70 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/style77/stockfish-or-not/internal/clock"
+)
+
+func TestFakeFiresInOrder(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	fired := make(chan int, 3)
+
+	fake.AfterFunc(3*time.Second, func() { fired <- 3 })
+	fake.AfterFunc(time.Second, func() { fired <- 1 })
+	stopped := fake.AfterFunc(2*time.Second, func() { fired <- 2 })
+
+	if !stopped.Stop() {
+		t.Error("Stop of a pending timer reported false")
+	}
+
+	fake.Advance(2 * time.Second)
+	if got := <-fired; got != 1 {
+		t.Errorf("first fired %d, want 1", got)
+	}
+
+	fake.Advance(time.Second)
+	if got := <-fired; got != 3 {
+		t.Errorf("then fired %d, want 3", got)
+	}
+
+	if now := fake.Now(); !now.Equal(time.Unix(3, 0)) {
+		t.Errorf("now = %s, want 3s after start", now)
+	}
+}
+
+func TestFakeSleepWaitsForAdvance(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	woke := make(chan struct{})
+
+	go func() {
+		fake.Sleep(time.Minute)
+		close(woke)
+	}()
+
+	fake.BlockUntil(1)
+	fake.Advance(59 * time.Second)
+
+	select {
+	case <-woke:
+		t.Fatal("sleep ended early")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	fake.Advance(time.Second)
+	<-woke
+}
+
+func TestFakeTicker(t *testing.T) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	ticker := fake.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for i := 1; i <= 3; i++ {
+		fake.Advance(time.Second)
+		if tick := <-ticker.C(); !tick.Equal(time.Unix(int64(i), 0)) {
+			t.Errorf("tick %d at %s", i, tick)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package internal
+
+import (
+	"log"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/game"
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/utils"
+)
+
+// the AI takes a moment before answering a draw offer, like a human would
+const (
+	drawReplyDelayFrom = 2 * time.Second
+	drawReplyDelayTo   = 8 * time.Second
+)
+
+// ProcessCommand hands the game commands that are not moves to the
+// player's room: resigning, draw offers and aborting.
+func (app *App) ProcessCommand(player *models.Player, command protocol.InboundType) {
+	app.postCommand(player, commandEvent{player: player, command: command})
+}
+
+// ProcessDrawClaim ends the game in a draw if the board allows the claim.
+func (app *App) ProcessDrawClaim(player *models.Player, method string) {
+	app.postCommand(player, commandEvent{player: player, command: protocol.TypeClaimDraw, method: method})
+}
+
+func (app *App) postCommand(player *models.Player, event commandEvent) {
+	room := playerRoom(player)
+	if room == nil {
+		log.Println("Player is not in a room.")
+		return
+	}
+
+	if !post(room, event) {
+		log.Println("Dropped", event.command, "for closed room", room.ID)
+	}
+}
+
+func (app *App) runCommand(room *models.Room, event commandEvent) {
+	player := event.player
+
+	var commandErr *game.Error
+	switch event.command {
+	case protocol.TypeResign:
+		commandErr = app.resign(player, room)
+	case protocol.TypeOfferDraw:
+		commandErr = app.offerDraw(player, room)
+	case protocol.TypeAcceptDraw:
+		commandErr = app.acceptDraw(player, room)
+	case protocol.TypeDeclineDraw:
+		commandErr = app.declineDraw(player, room)
+	case protocol.TypeAbort:
+		commandErr = app.abort(player, room)
+	case protocol.TypeClaimDraw:
+		commandErr = app.claimDraw(player, room, event.method)
+	}
+
+	if commandErr != nil {
+		log.Println("Rejected", event.command, "in room", room.ID+":", commandErr)
+		game.RejectCommand(player, room, event.command, commandErr)
+	}
+}
+
+func (app *App) resign(player *models.Player, room *models.Room) *game.Error {
+	if commandErr := game.CanResign(room); commandErr != nil {
+		return commandErr
+	}
+
+	app.endGame(player, room, "resignation", &utils.GameResult{
+		Outcome:       utils.WinFor(getOpponentColor(*player.Color)),
+		OutcomeReason: "resignation",
+		Method:        chess.Resignation,
+	})
+	return nil
+}
+
+func (app *App) offerDraw(player *models.Player, room *models.Room) *game.Error {
+	if commandErr := game.OfferDraw(room, player); commandErr != nil {
+		return commandErr
+	}
+
+	opponent := getOpponent(room, player)
+	if opponent.IsAI {
+		app.considerAIDrawOffer(room, opponent)
+		return nil
+	}
+
+	err := utils.SafelyNotifyPlayer(opponent, protocol.NewEnvelope(protocol.StateDrawOffered, room.ID, "Opponent offers a draw", nil))
+	if err != nil {
+		log.Println("Error notifying opponent about draw offer:", err)
+	}
+	return nil
+}
+
+func (app *App) acceptDraw(player *models.Player, room *models.Room) *game.Error {
+	if commandErr := game.AnswerDrawOffer(room, player); commandErr != nil {
+		return commandErr
+	}
+
+	app.endGame(player, room, "draw agreed", &utils.GameResult{
+		Outcome:       chess.Draw,
+		OutcomeReason: "draw agreed",
+		Method:        chess.DrawOffer,
+	})
+	return nil
+}
+
+func (app *App) declineDraw(player *models.Player, room *models.Room) *game.Error {
+	if commandErr := game.AnswerDrawOffer(room, player); commandErr != nil {
+		return commandErr
+	}
+
+	err := utils.SafelyNotifyPlayer(getOpponent(room, player), protocol.NewEnvelope(protocol.StateDrawDeclined, room.ID, "Draw offer declined", nil))
+	if err != nil {
+		log.Println("Error notifying opponent about declined draw:", err)
+	}
+	return nil
+}
+
+func (app *App) abort(player *models.Player, room *models.Room) *game.Error {
+	if commandErr := game.CanAbort(room, player); commandErr != nil {
+		return commandErr
+	}
+
+	app.endGame(player, room, "aborted", &utils.GameResult{
+		Outcome:       chess.NoOutcome,
+		OutcomeReason: "aborted",
+	})
+	return nil
+}
+
+func (app *App) claimDraw(player *models.Player, room *models.Room, method string) *game.Error {
+	drawMethod, commandErr := game.ClaimDraw(room, player, method)
+	if commandErr != nil {
+		return commandErr
+	}
+
+	app.endGame(player, room, drawMethod.String(), &utils.GameResult{
+		Outcome:       chess.Draw,
+		OutcomeReason: drawMethod.String(),
+		Method:        drawMethod,
+	})
+	return nil
+}
+
+// considerAIDrawOffer lets the engine judge the position off the event loop,
+// the AI accepts when it is not better. It takes a moment before answering,
+// like a human would.
+func (app *App) considerAIDrawOffer(room *models.Room, aiPlayer *models.Player) {
+	position := game.EnginePosition(room)
+	plies := len(room.Moves)
+	aiToMove := room.Turn == aiPlayer
+	offerID := room.DrawOfferID
+	delay := app.randomDuration(drawReplyDelayFrom, drawReplyDelayTo)
+
+	go func() {
+		app.Clock.Sleep(delay)
+
+		accepts, err := aiPlayer.AI.AcceptsDraw(position, plies, aiToMove)
+		post(room, aiDrawAnswerEvent{player: aiPlayer, offerID: offerID, accepts: accepts, err: err})
+	}()
+}
+
+// answerAIDrawOffer answers the draw offer the AI considered. A move made in
+// the meantime already declined it, and a newer offer gets its own answer.
+func (app *App) answerAIDrawOffer(room *models.Room, answer aiDrawAnswerEvent) {
+	if answer.offerID != room.DrawOfferID {
+		log.Println("AI answer to an earlier draw offer dropped in room", room.ID)
+		return
+	}
+
+	if answer.err != nil {
+		log.Println("Error evaluating draw offer, declining:", answer.err)
+	}
+
+	var commandErr *game.Error
+	if answer.accepts {
+		commandErr = app.acceptDraw(answer.player, room)
+	} else {
+		commandErr = app.declineDraw(answer.player, room)
+	}
+
+	if commandErr != nil {
+		log.Println("AI could not answer draw offer in room", room.ID+":", commandErr)
+	}
+}
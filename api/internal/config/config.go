@@ -0,0 +1,496 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/style77/stockfish-or-not/internal/engine"
+	"github.com/style77/stockfish-or-not/internal/timer"
+)
+
+// Duration is a time.Duration that is written as "4s" or "1m30s" in config
+// files and environment variables.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("duration must be a string like \"4s\": %w", err)
+	}
+
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = parsed
+	return nil
+}
+
+type Config struct {
+	Addr        string `json:"addr"`
+	ArchivePath string `json:"archivePath"`
+
+	Connection  ConnectionConfig  `json:"connection"`
+	Matchmaking MatchmakingConfig `json:"matchmaking"`
+	Game        GameConfig        `json:"game"`
+	AI          AIConfig          `json:"ai"`
+}
+
+type ConnectionConfig struct {
+	PingInterval Duration `json:"pingInterval"`
+	PongTimeout  Duration `json:"pongTimeout"`
+}
+
+type MatchmakingConfig struct {
+	AIProbability       float64  `json:"aiProbability"`
+	LookingIntervalFrom Duration `json:"lookingIntervalFrom"`
+	LookingIntervalTo   Duration `json:"lookingIntervalTo"`
+	OpponentTimeout     Duration `json:"opponentTimeout"`
+}
+
+type GameConfig struct {
+	// Time is the sudden death control used when no TimeControls are given.
+	Time         Duration            `json:"time"`
+	TimeControls []TimeControlConfig `json:"timeControls"`
+	// TickInterval is how often both players are sent the running clock.
+	// Move messages already carry both clocks, so it is off (zero) by default.
+	TickInterval   Duration `json:"tickInterval"`
+	GuessTimeout   Duration `json:"guessTimeout"`
+	ReconnectGrace Duration `json:"reconnectGrace"`
+}
+
+type PeriodConfig struct {
+	Moves int      `json:"moves"` // 0 means the rest of the game
+	Time  Duration `json:"time"`
+}
+
+type TimeControlConfig struct {
+	Name      string         `json:"name"`
+	Periods   []PeriodConfig `json:"periods"`
+	Increment Duration       `json:"increment"`
+	Delay     Duration       `json:"delay"`
+	DelayMode string         `json:"delayMode"` // "", "simple" or "bronstein"
+}
+
+type AIConfig struct {
+	EnginePath string `json:"enginePath"`
+	PoolSize   int    `json:"poolSize"`
+	MaxDepth   int    `json:"maxDepth"`
+	// MultiPV is how many candidate moves the AI picks its move from
+	MultiPV int `json:"multiPV"`
+	// MoveDelayFrom and MoveDelayTo bound the AI's think time, within them
+	// it depends on the clock and the position
+	MoveDelayFrom Duration `json:"moveDelayFrom"`
+	MoveDelayTo   Duration `json:"moveDelayTo"`
+	// Elo is the distribution AI ratings are drawn from
+	Elo EloConfig `json:"elo"`
+
+	// Engines and Personas describe the AI opponents, without them a single
+	// "stockfish" persona plays with the engine at EnginePath. Engines whose
+	// binary is missing are replaced by the built-in engine.
+	Engines  []EngineConfig  `json:"engines"`
+	Personas []PersonaConfig `json:"personas"`
+}
+
+// EngineConfig is a UCI binary AI opponents can play with.
+type EngineConfig struct {
+	Name     string            `json:"name"`
+	Path     string            `json:"path"`
+	PoolSize int               `json:"poolSize"` // 0 uses ai.poolSize
+	Options  map[string]string `json:"options"`  // set when a process starts, e.g. WeightsFile
+}
+
+// PersonaConfig is a kind of AI opponent, zero values fall back to the ai
+// settings.
+type PersonaConfig struct {
+	Name    string            `json:"name"`
+	Engine  string            `json:"engine"`
+	Options map[string]string `json:"options"` // set for the persona's searches
+	Elo     EloConfig         `json:"elo"`
+	// EngineElo is the strength of an engine that can't be limited by
+	// options, e.g. Maia weights
+	EngineElo     int      `json:"engineElo"`
+	MoveDelayFrom Duration `json:"moveDelayFrom"`
+	MoveDelayTo   Duration `json:"moveDelayTo"`
+}
+
+// EloConfig is uniform between Min and Max when StdDev is zero, otherwise
+// normal around Mean and cut off at Min and Max.
+type EloConfig struct {
+	Min    int `json:"min"`
+	Max    int `json:"max"`
+	Mean   int `json:"mean"`
+	StdDev int `json:"stdDev"`
+}
+
+func (e EloConfig) Range() engine.EloRange {
+	return engine.EloRange{Min: e.Min, Max: e.Max, Mean: e.Mean, StdDev: e.StdDev}
+}
+
+func Default() *Config {
+	return &Config{
+		Addr:        ":8080",
+		ArchivePath: "games.jsonl",
+		Connection: ConnectionConfig{
+			PingInterval: Duration{10 * time.Second},
+			PongTimeout:  Duration{30 * time.Second},
+		},
+		Matchmaking: MatchmakingConfig{
+			AIProbability:       0.5,
+			LookingIntervalFrom: Duration{4 * time.Second},
+			LookingIntervalTo:   Duration{10 * time.Second},
+			OpponentTimeout:     Duration{7 * time.Second},
+		},
+		Game: GameConfig{
+			Time:           Duration{60 * time.Second},
+			GuessTimeout:   Duration{60 * time.Second},
+			ReconnectGrace: Duration{15 * time.Second},
+		},
+		AI: AIConfig{
+			EnginePath:    "../stockfish",
+			PoolSize:      4,
+			MaxDepth:      10,
+			MultiPV:       5,
+			MoveDelayFrom: Duration{time.Second},
+			MoveDelayTo:   Duration{20 * time.Second},
+			Elo: EloConfig{
+				Min:  100,
+				Max:  2100,
+				Mean: 1100,
+			},
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, then the JSON file at path
+// (if any), then environment variables and finally the command line flags.
+func Load(path string, flags *Flags) (*Config, error) {
+	cfg := Default()
+
+	if path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if flags != nil {
+		if err := flags.apply(cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("reading config file: %w", err)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("parsing config file %s: %w", path, err)
+	}
+
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	for _, s := range settings {
+		value, ok := os.LookupEnv(s.env)
+		if !ok {
+			continue
+		}
+
+		if err := s.set(c, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", s.env, err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Addr == "" {
+		errs = append(errs, errors.New("addr must not be empty"))
+	}
+	if c.ArchivePath == "" {
+		errs = append(errs, errors.New("archivePath must not be empty"))
+	}
+
+	if c.Connection.PingInterval.Duration <= 0 {
+		errs = append(errs, errors.New("connection.pingInterval must be positive"))
+	}
+	if c.Connection.PongTimeout.Duration <= c.Connection.PingInterval.Duration {
+		errs = append(errs, errors.New("connection.pongTimeout must be longer than pingInterval"))
+	}
+
+	if c.Matchmaking.AIProbability < 0 || c.Matchmaking.AIProbability > 1 {
+		errs = append(errs, errors.New("matchmaking.aiProbability must be between 0 and 1"))
+	}
+	if c.Matchmaking.LookingIntervalFrom.Duration < 0 {
+		errs = append(errs, errors.New("matchmaking.lookingIntervalFrom must not be negative"))
+	}
+	if c.Matchmaking.LookingIntervalTo.Duration < c.Matchmaking.LookingIntervalFrom.Duration {
+		errs = append(errs, errors.New("matchmaking.lookingIntervalTo must not be shorter than lookingIntervalFrom"))
+	}
+	if c.Matchmaking.OpponentTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("matchmaking.opponentTimeout must be positive"))
+	}
+
+	if c.Game.Time.Duration < time.Second {
+		errs = append(errs, errors.New("game.time must be at least one second"))
+	}
+	for i, tc := range c.Game.TimeControls {
+		if err := tc.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("game.timeControls[%d]: %w", i, err))
+		}
+	}
+	if c.Game.TickInterval.Duration < 0 {
+		errs = append(errs, errors.New("game.tickInterval must not be negative"))
+	}
+	if c.Game.GuessTimeout.Duration <= 0 {
+		errs = append(errs, errors.New("game.guessTimeout must be positive"))
+	}
+	if c.Game.ReconnectGrace.Duration < 0 {
+		errs = append(errs, errors.New("game.reconnectGrace must not be negative"))
+	}
+
+	if c.AI.EnginePath == "" {
+		errs = append(errs, errors.New("ai.enginePath must not be empty"))
+	}
+	if c.AI.PoolSize < 1 {
+		errs = append(errs, errors.New("ai.poolSize must be at least 1"))
+	}
+	if c.AI.MaxDepth < 1 {
+		errs = append(errs, errors.New("ai.maxDepth must be at least 1"))
+	}
+	if c.AI.MultiPV < 1 {
+		errs = append(errs, errors.New("ai.multiPV must be at least 1"))
+	}
+	if c.AI.MoveDelayFrom.Duration < 0 {
+		errs = append(errs, errors.New("ai.moveDelayFrom must not be negative"))
+	}
+	if c.AI.MoveDelayTo.Duration < c.AI.MoveDelayFrom.Duration {
+		errs = append(errs, errors.New("ai.moveDelayTo must not be shorter than moveDelayFrom"))
+	}
+	if err := c.AI.Elo.validate(); err != nil {
+		errs = append(errs, fmt.Errorf("ai.elo: %w", err))
+	}
+
+	engines := map[string]bool{}
+	for i, ec := range c.AI.Engines {
+		if err := ec.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("ai.engines[%d]: %w", i, err))
+		}
+		if engines[ec.Name] {
+			errs = append(errs, fmt.Errorf("ai.engines[%d]: duplicate name %q", i, ec.Name))
+		}
+		engines[ec.Name] = true
+	}
+	for i, pc := range c.AI.Personas {
+		if err := pc.validate(c.AI.EngineConfigs()); err != nil {
+			errs = append(errs, fmt.Errorf("ai.personas[%d]: %w", i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+	}
+
+	return nil
+}
+
+func (e EloConfig) validate() error {
+	if e.Min < 1 {
+		return errors.New("min must be at least 1")
+	}
+	if e.Max < e.Min {
+		return errors.New("max must not be lower than min")
+	}
+	if e.StdDev < 0 {
+		return errors.New("stdDev must not be negative")
+	}
+	if e.StdDev > 0 && (e.Mean < e.Min || e.Mean > e.Max) {
+		return errors.New("mean must be between min and max")
+	}
+	return nil
+}
+
+func (ec EngineConfig) validate() error {
+	if ec.Name == "" {
+		return errors.New("name must not be empty")
+	}
+	if ec.Path == "" {
+		return errors.New("path must not be empty")
+	}
+	if ec.PoolSize < 0 {
+		return errors.New("poolSize must not be negative")
+	}
+	return nil
+}
+
+func (pc PersonaConfig) validate(engines []EngineConfig) error {
+	if pc.Name == "" {
+		return errors.New("name must not be empty")
+	}
+
+	known := false
+	for _, ec := range engines {
+		known = known || ec.Name == pc.Engine
+	}
+	if !known {
+		return fmt.Errorf("unknown engine %q", pc.Engine)
+	}
+
+	if pc.Elo != (EloConfig{}) {
+		if err := pc.Elo.validate(); err != nil {
+			return fmt.Errorf("elo: %w", err)
+		}
+	}
+	if pc.EngineElo < 0 {
+		return errors.New("engineElo must not be negative")
+	}
+	if pc.MoveDelayFrom.Duration < 0 || pc.MoveDelayTo.Duration < pc.MoveDelayFrom.Duration {
+		return errors.New("moveDelayTo must not be shorter than moveDelayFrom")
+	}
+	return nil
+}
+
+func (tc TimeControlConfig) validate() error {
+	if len(tc.Periods) == 0 {
+		return errors.New("at least one period is required")
+	}
+
+	for i, period := range tc.Periods {
+		if period.Time.Duration < time.Second {
+			return fmt.Errorf("period %d must be at least one second", i)
+		}
+		if period.Moves < 0 {
+			return fmt.Errorf("period %d must not have a negative number of moves", i)
+		}
+		if period.Moves == 0 && i != len(tc.Periods)-1 {
+			return fmt.Errorf("only the last period may last for the rest of the game")
+		}
+	}
+
+	if tc.Increment.Duration < 0 || tc.Delay.Duration < 0 {
+		return errors.New("increment and delay must not be negative")
+	}
+
+	switch timer.DelayMode(tc.DelayMode) {
+	case timer.DelayNone, timer.DelaySimple, timer.DelayBronstein:
+	default:
+		return fmt.Errorf("unknown delayMode %q", tc.DelayMode)
+	}
+
+	return nil
+}
+
+func (tc TimeControlConfig) TimeControl() timer.TimeControl {
+	periods := make([]timer.Period, 0, len(tc.Periods))
+	for _, period := range tc.Periods {
+		periods = append(periods, timer.Period{Moves: period.Moves, Time: period.Time.Duration})
+	}
+
+	control := timer.TimeControl{
+		Name:      tc.Name,
+		Periods:   periods,
+		Increment: tc.Increment.Duration,
+		Delay:     tc.Delay.Duration,
+		DelayMode: timer.DelayMode(tc.DelayMode),
+	}
+
+	if control.Name == "" {
+		control.Name = control.String()
+	}
+
+	return control
+}
+
+// Controls lists the time controls rooms are played with.
+func (c GameConfig) Controls() []timer.TimeControl {
+	if len(c.TimeControls) == 0 {
+		control := timer.SuddenDeath(c.Time.Duration)
+		control.Name = control.String()
+		return []timer.TimeControl{control}
+	}
+
+	controls := make([]timer.TimeControl, 0, len(c.TimeControls))
+	for _, tc := range c.TimeControls {
+		controls = append(controls, tc.TimeControl())
+	}
+	return controls
+}
+
+// EngineConfigs lists the engines AI opponents play with.
+func (c AIConfig) EngineConfigs() []EngineConfig {
+	if len(c.Engines) == 0 {
+		return []EngineConfig{{Name: "stockfish", Path: c.EnginePath, PoolSize: c.PoolSize}}
+	}
+
+	engines := make([]EngineConfig, 0, len(c.Engines))
+	for _, ec := range c.Engines {
+		if ec.PoolSize == 0 {
+			ec.PoolSize = c.PoolSize
+		}
+		engines = append(engines, ec)
+	}
+	return engines
+}
+
+// PersonaList lists the kinds of AI opponents, with the ai settings filled in
+// where a persona leaves them out.
+func (c AIConfig) PersonaList() []engine.Persona {
+	configs := c.Personas
+	if len(configs) == 0 {
+		configs = []PersonaConfig{{Name: "stockfish", Engine: c.EngineConfigs()[0].Name}}
+	}
+
+	personas := make([]engine.Persona, 0, len(configs))
+	for _, pc := range configs {
+		elo := pc.Elo
+		if elo == (EloConfig{}) {
+			elo = c.Elo
+		}
+
+		timing := engine.ThinkTimeModel{Min: c.MoveDelayFrom.Duration, Max: c.MoveDelayTo.Duration}
+		if pc.MoveDelayTo.Duration > 0 {
+			timing = engine.ThinkTimeModel{Min: pc.MoveDelayFrom.Duration, Max: pc.MoveDelayTo.Duration}
+		}
+
+		personas = append(personas, engine.Persona{
+			Name:      pc.Name,
+			Engine:    pc.Engine,
+			Options:   pc.Options,
+			Elo:       elo.Range(),
+			EngineElo: pc.EngineElo,
+			Timing:    timing,
+		})
+	}
+	return personas
+}
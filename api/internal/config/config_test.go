@@ -0,0 +1,127 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeConfig writes the JSON config to a file and returns its path.
+func writeConfig(t *testing.T, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     string
+		env      map[string]string
+		args     []string
+		wantAddr string
+		wantTime time.Duration
+	}{
+		{
+			name:     "defaults",
+			wantAddr: Default().Addr,
+			wantTime: Default().Game.Time.Duration,
+		},
+		{
+			name:     "file over defaults",
+			file:     `{"addr": ":1", "game": {"time": "1m"}}`,
+			wantAddr: ":1",
+			wantTime: time.Minute,
+		},
+		{
+			name:     "env over file",
+			file:     `{"addr": ":1", "game": {"time": "1m"}}`,
+			env:      map[string]string{"SFON_ADDR": ":2"},
+			wantAddr: ":2",
+			wantTime: time.Minute,
+		},
+		{
+			name:     "flags over env",
+			file:     `{"addr": ":1", "game": {"time": "1m"}}`,
+			env:      map[string]string{"SFON_ADDR": ":2", "SFON_GAME_TIME": "2m"},
+			args:     []string{"-addr", ":3"},
+			wantAddr: ":3",
+			wantTime: 2 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			path := ""
+			if tt.file != "" {
+				path = writeConfig(t, tt.file)
+			}
+
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			flags := RegisterFlags(fs)
+			if err := fs.Parse(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			cfg, err := Load(path, flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.Addr != tt.wantAddr {
+				t.Errorf("addr = %q, want %q", cfg.Addr, tt.wantAddr)
+			}
+			if cfg.Game.Time.Duration != tt.wantTime {
+				t.Errorf("game time = %s, want %s", cfg.Game.Time.Duration, tt.wantTime)
+			}
+		})
+	}
+}
+
+func TestLoadRejectsInvalidConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"unknown key", `{"adress": ":1"}`, nil, `unknown field "adress"`},
+		{"duration not a string", `{"game": {"time": 60}}`, nil, "duration must be a string"},
+		{"negative tick interval", `{"game": {"tickInterval": "-1s"}}`, nil, "game.tickInterval must not be negative"},
+		{"negative reconnect grace", `{"game": {"reconnectGrace": "-5s"}}`, nil, "game.reconnectGrace must not be negative"},
+		{"game time too short", `{"game": {"time": "500ms"}}`, nil, "game.time must be at least one second"},
+		{"probability out of range", `{"matchmaking": {"aiProbability": 1.5}}`, nil, "matchmaking.aiProbability must be between 0 and 1"},
+		{"intervals swapped", `{"matchmaking": {"lookingIntervalFrom": "5s", "lookingIntervalTo": "1s"}}`, nil, "lookingIntervalTo must not be shorter"},
+		{"pong before ping", `{"connection": {"pingInterval": "10s", "pongTimeout": "5s"}}`, nil, "pongTimeout must be longer"},
+		{"persona without engine", `{"ai": {"personas": [{"name": "p", "engine": "missing"}]}}`, nil, "ai.personas[0]"},
+		{"invalid env", `{}`, map[string]string{"SFON_MAX_DEPTH": "deep"}, "invalid SFON_MAX_DEPTH"},
+		{"negative env duration", `{}`, map[string]string{"SFON_GUESS_TIMEOUT": "-1s"}, "game.guessTimeout must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+
+			_, err := Load(writeConfig(t, tt.file), nil)
+			if err == nil {
+				t.Fatalf("loaded the config, want an error containing %q", tt.wantErr)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error %q, want it to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
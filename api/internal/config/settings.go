@@ -0,0 +1,110 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// setting is a config value that can be overridden from the environment and
+// the command line.
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	field func(*Config) interface{}
+}
+
+var settings = []setting{
+	{"addr", "SFON_ADDR", "address to listen on", func(c *Config) interface{} { return &c.Addr }},
+	{"archive", "SFON_ARCHIVE_PATH", "file the finished games are archived to", func(c *Config) interface{} { return &c.ArchivePath }},
+
+	{"ping-interval", "SFON_PING_INTERVAL", "how often connections are pinged", func(c *Config) interface{} { return &c.Connection.PingInterval }},
+	{"pong-timeout", "SFON_PONG_TIMEOUT", "how long without a pong before a connection is considered dead", func(c *Config) interface{} { return &c.Connection.PongTimeout }},
+
+	{"ai-probability", "SFON_AI_PROBABILITY", "chance of being matched with an AI", func(c *Config) interface{} { return &c.Matchmaking.AIProbability }},
+	{"looking-interval-from", "SFON_LOOKING_INTERVAL_FROM", "shortest fake matchmaking wait before an AI game", func(c *Config) interface{} { return &c.Matchmaking.LookingIntervalFrom }},
+	{"looking-interval-to", "SFON_LOOKING_INTERVAL_TO", "longest fake matchmaking wait before an AI game", func(c *Config) interface{} { return &c.Matchmaking.LookingIntervalTo }},
+	{"opponent-timeout", "SFON_OPPONENT_TIMEOUT", "how long to look for a human before falling back to an AI", func(c *Config) interface{} { return &c.Matchmaking.OpponentTimeout }},
+
+	{"game-time", "SFON_GAME_TIME", "time on each player's clock", func(c *Config) interface{} { return &c.Game.Time }},
+	{"tick-interval", "SFON_TICK_INTERVAL", "how often the running clock is broadcast, 0 to disable", func(c *Config) interface{} { return &c.Game.TickInterval }},
+	{"guess-timeout", "SFON_GUESS_TIMEOUT", "how long players have to guess after the game", func(c *Config) interface{} { return &c.Game.GuessTimeout }},
+	{"reconnect-grace", "SFON_RECONNECT_GRACE", "how long a disconnected player has to come back before forfeiting", func(c *Config) interface{} { return &c.Game.ReconnectGrace }},
+
+	{"engine", "SFON_ENGINE_PATH", "path to the UCI engine binary", func(c *Config) interface{} { return &c.AI.EnginePath }},
+	{"engine-pool-size", "SFON_ENGINE_POOL_SIZE", "number of engine processes", func(c *Config) interface{} { return &c.AI.PoolSize }},
+	{"max-depth", "SFON_MAX_DEPTH", "maximum engine search depth", func(c *Config) interface{} { return &c.AI.MaxDepth }},
+	{"multi-pv", "SFON_MULTI_PV", "candidate moves the AI picks from", func(c *Config) interface{} { return &c.AI.MultiPV }},
+	{"ai-move-delay-from", "SFON_AI_MOVE_DELAY_FROM", "shortest AI thinking time", func(c *Config) interface{} { return &c.AI.MoveDelayFrom }},
+	{"ai-move-delay-to", "SFON_AI_MOVE_DELAY_TO", "longest AI thinking time", func(c *Config) interface{} { return &c.AI.MoveDelayTo }},
+	{"ai-elo-min", "SFON_AI_ELO_MIN", "lowest AI rating", func(c *Config) interface{} { return &c.AI.Elo.Min }},
+	{"ai-elo-max", "SFON_AI_ELO_MAX", "highest AI rating", func(c *Config) interface{} { return &c.AI.Elo.Max }},
+	{"ai-elo-mean", "SFON_AI_ELO_MEAN", "mean AI rating, only used with a standard deviation", func(c *Config) interface{} { return &c.AI.Elo.Mean }},
+	{"ai-elo-stddev", "SFON_AI_ELO_STDDEV", "standard deviation of AI ratings, 0 draws them uniformly", func(c *Config) interface{} { return &c.AI.Elo.StdDev }},
+}
+
+func (s setting) set(c *Config, value string) error {
+	switch field := s.field(c).(type) {
+	case *string:
+		*field = value
+	case *int:
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *float64:
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return err
+		}
+		*field = parsed
+	case *Duration:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		field.Duration = parsed
+	default:
+		return fmt.Errorf("unsupported setting type %T", field)
+	}
+
+	return nil
+}
+
+type flagValue struct {
+	setting setting
+	value   string
+}
+
+// Flags collects the config flags given on the command line, they are only
+// applied in Load so that they take precedence over the file and environment.
+type Flags struct {
+	values []flagValue
+}
+
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{}
+
+	for _, s := range settings {
+		s := s
+		fs.Func(s.flag, s.usage+" (env "+s.env+")", func(value string) error {
+			flags.values = append(flags.values, flagValue{setting: s, value: value})
+			return nil
+		})
+	}
+
+	return flags
+}
+
+func (f *Flags) apply(c *Config) error {
+	for _, v := range f.values {
+		if err := v.setting.set(c, v.value); err != nil {
+			return fmt.Errorf("invalid -%s: %w", v.setting.flag, err)
+		}
+	}
+
+	return nil
+}
@@ -1,14 +0,0 @@
-package constants
-
-const (
-	AIPlayerPosibility             = 0.5
-	PlayerLookingIntervalRangeFrom = 4
-	PlayerLookingIntervalRangeTo   = 10
-	TimeoutForOpponent             = 7
-	GameTime                       = 60
-	MaxDepth                       = 10
-
-	// AI
-	AIMoveWaitTimeFrom = 4
-	AIMoveWaitTimeTo   = 20
-)
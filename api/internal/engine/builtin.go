@@ -0,0 +1,324 @@
+package engine
+
+import (
+	"errors"
+	"log"
+	"math"
+	"sort"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+// the built-in engine's strength knob is UCI_Elo, like Stockfish's, and maps
+// a rating onto search depth and noise added to the evaluation
+const (
+	builtinMinElo = 400
+	builtinMaxElo = 1800
+
+	builtinMaxDepth = 4
+	builtinMaxNodes = 200000 // keeps a search within a few seconds
+
+	// evaluation noise in centipawns at builtinMinElo, none at full strength
+	builtinMaxNoise = 200
+
+	mateValue       = 100000
+	quiescenceDepth = 4
+)
+
+var errNodeLimit = errors.New("node limit reached")
+
+// BuiltinEngine is a small alpha-beta engine written in Go, used when no UCI
+// binary is available. It is stateless and safe for concurrent use.
+type BuiltinEngine struct {
+	rand random.Rand // noise of limited strength searches
+}
+
+func NewBuiltinEngine(rnd random.Rand) *BuiltinEngine {
+	return &BuiltinEngine{rand: rnd}
+}
+
+// OpenEngine returns the UCI engine at path, or the built-in engine drawing
+// from rnd when the binary cannot be started or doesn't speak UCI. The first
+// engine of the pool is started right away to find out.
+func OpenEngine(path string, poolSize int, setup map[string]string, rnd random.Rand) Engine {
+	pool := NewEnginePool(path, poolSize, setup)
+
+	if _, err := pool.Options(); err != nil {
+		pool.Close()
+		log.Println("No UCI engine at", path+", using the built-in engine:", err)
+		return NewBuiltinEngine(rnd)
+	}
+
+	return pool
+}
+
+func (*BuiltinEngine) Options() (map[string]EngineOption, error) {
+	return map[string]EngineOption{
+		"UCI_LimitStrength": {Type: "check", Default: "false"},
+		"UCI_Elo":           {Type: "spin", Default: "1800", Min: builtinMinElo, Max: builtinMaxElo},
+		"MultiPV":           {Type: "spin", Default: "1", Min: 1, Max: 256},
+	}, nil
+}
+
+func (*BuiltinEngine) Close() {
+}
+
+// Search runs an iteratively deepened alpha-beta search. Limited strength
+// searches shallower and plays its move by a noisy evaluation, the reported
+// candidates keep their true scores.
+func (b *BuiltinEngine) Search(gameID string, options SearchOptions, position Position, depth int) (*SearchResult, error) {
+	root, err := builtinPosition(position)
+	if err != nil {
+		return nil, &EngineError{Op: "position", Err: err}
+	}
+
+	elo := builtinMaxElo
+	if options.LimitStrength {
+		elo = min(max(options.Elo, builtinMinElo), builtinMaxElo)
+	}
+
+	s := &builtinSearch{}
+	lines, err := s.root(root, min(depth, builtinDepth(elo)), max(options.MultiPV, 1))
+	if err != nil {
+		return nil, &EngineError{Op: "search", Err: err}
+	}
+
+	noise := float64(builtinMaxNoise*(builtinMaxElo-elo)) / float64(builtinMaxElo-builtinMinElo)
+	bestMove, bestNoisy := "", math.Inf(-1)
+	for _, line := range lines {
+		noisy := float64(line.score) + b.rand.NormFloat64()*noise
+		if noisy > bestNoisy {
+			bestMove, bestNoisy = line.move, noisy
+		}
+	}
+
+	result := &SearchResult{BestMove: bestMove, Score: builtinScore(lines[0].score)}
+	for _, line := range lines {
+		result.Candidates = append(result.Candidates, Candidate{Move: line.move, Score: builtinScore(line.score)})
+	}
+
+	return result, nil
+}
+
+// builtinDepth is how many plies the built-in engine looks ahead at elo.
+func builtinDepth(elo int) int {
+	step := (builtinMaxElo - builtinMinElo) / builtinMaxDepth
+	return min(1+(elo-builtinMinElo)/step, builtinMaxDepth)
+}
+
+func builtinPosition(position Position) (*chess.Position, error) {
+	game := chess.NewGame()
+	if position.FEN != "" {
+		fen, err := chess.FEN(position.FEN)
+		if err != nil {
+			return nil, err
+		}
+		game = chess.NewGame(fen)
+	}
+
+	current := game.Position()
+	for _, uciMove := range position.Moves {
+		move, err := (chess.UCINotation{}).Decode(current, uciMove)
+		if err != nil {
+			return nil, err
+		}
+		current = current.Update(move)
+	}
+
+	return current, nil
+}
+
+// builtinScore turns a search score into a Score, mates are counted in
+// moves like UCI engines do.
+func builtinScore(score int) Score {
+	switch {
+	case score > mateValue-1000:
+		return Score{Mate: (mateValue - score + 1) / 2}
+	case score < -mateValue+1000:
+		return Score{Mate: -(mateValue + score + 1) / 2}
+	}
+	return Score{Centipawns: score}
+}
+
+type builtinLine struct {
+	move  string
+	score int
+}
+
+type builtinSearch struct {
+	nodes int
+}
+
+// root searches every move of the position to depth and returns the best
+// lines, best first. A search that runs out of nodes returns the lines of
+// the last completed depth.
+func (s *builtinSearch) root(position *chess.Position, depth, multiPV int) ([]builtinLine, error) {
+	moves := orderMoves(position, position.ValidMoves())
+	if len(moves) == 0 {
+		return nil, ErrNoMove
+	}
+
+	var lines []builtinLine
+	for d := 1; d <= depth; d++ {
+		completed, err := s.rootDepth(position, moves, d, multiPV)
+		if err != nil {
+			break
+		}
+		lines = completed
+
+		// search the best moves first on the next iteration
+		order := map[string]int{}
+		for i, line := range lines {
+			order[line.move] = len(lines) - i
+		}
+		sort.SliceStable(moves, func(i, j int) bool {
+			return order[encodeMove(position, moves[i])] > order[encodeMove(position, moves[j])]
+		})
+	}
+
+	if lines == nil {
+		// not even depth one finished, play anything legal
+		lines = []builtinLine{{move: encodeMove(position, moves[0])}}
+	}
+
+	return lines, nil
+}
+
+func (s *builtinSearch) rootDepth(position *chess.Position, moves []*chess.Move, depth, multiPV int) ([]builtinLine, error) {
+	var lines []builtinLine
+
+	for _, move := range moves {
+		// moves that can't reach the multiPV best only need a bound
+		alpha := -mateValue - 1
+		if len(lines) >= multiPV {
+			alpha = lines[multiPV-1].score
+		}
+
+		score, err := s.negamax(position.Update(move), depth-1, -mateValue-1, -alpha, 1)
+		if err != nil {
+			return nil, err
+		}
+		score = -score
+
+		if len(lines) < multiPV || score > alpha {
+			lines = append(lines, builtinLine{move: encodeMove(position, move), score: score})
+			sort.SliceStable(lines, func(i, j int) bool { return lines[i].score > lines[j].score })
+			if len(lines) > multiPV {
+				lines = lines[:multiPV]
+			}
+		}
+	}
+
+	return lines, nil
+}
+
+func (s *builtinSearch) negamax(position *chess.Position, depth, alpha, beta, ply int) (int, error) {
+	s.nodes++
+	if s.nodes > builtinMaxNodes {
+		return 0, errNodeLimit
+	}
+
+	moves := position.ValidMoves()
+	if len(moves) == 0 {
+		if position.Status() == chess.Checkmate {
+			return -mateValue + ply, nil
+		}
+		return 0, nil
+	}
+
+	if position.HalfMoveClock() >= 100 {
+		return 0, nil
+	}
+
+	if depth <= 0 {
+		return s.quiescence(position, alpha, beta, quiescenceDepth)
+	}
+
+	for _, move := range orderMoves(position, moves) {
+		score, err := s.negamax(position.Update(move), depth-1, -beta, -alpha, ply+1)
+		if err != nil {
+			return 0, err
+		}
+		score = -score
+
+		if score >= beta {
+			return beta, nil
+		}
+		alpha = max(alpha, score)
+	}
+
+	return alpha, nil
+}
+
+// quiescence only follows captures, so the evaluation isn't taken in the
+// middle of an exchange.
+func (s *builtinSearch) quiescence(position *chess.Position, alpha, beta, depth int) (int, error) {
+	standPat := evaluate(position)
+	if standPat >= beta {
+		return beta, nil
+	}
+	if depth == 0 {
+		return max(standPat, alpha), nil
+	}
+	alpha = max(alpha, standPat)
+
+	for _, move := range orderMoves(position, position.ValidMoves()) {
+		if !move.HasTag(chess.Capture) {
+			break // captures are ordered first
+		}
+
+		s.nodes++
+		if s.nodes > builtinMaxNodes {
+			return 0, errNodeLimit
+		}
+
+		score, err := s.quiescence(position.Update(move), -beta, -alpha, depth-1)
+		if err != nil {
+			return 0, err
+		}
+		score = -score
+
+		if score >= beta {
+			return beta, nil
+		}
+		alpha = max(alpha, score)
+	}
+
+	return alpha, nil
+}
+
+// orderMoves puts captures first, the most valuable victim taken by the
+// least valuable attacker leading, then promotions and checks.
+func orderMoves(position *chess.Position, moves []*chess.Move) []*chess.Move {
+	ordered := make([]*chess.Move, len(moves))
+	copy(ordered, moves)
+
+	board := position.Board()
+	priority := func(move *chess.Move) int {
+		p := 0
+		if move.HasTag(chess.Capture) {
+			victim := pieceValues[chess.Pawn] // en passant leaves the square empty
+			if piece := board.Piece(move.S2()); piece != chess.NoPiece {
+				victim = pieceValues[piece.Type()]
+			}
+			p += 10000 + 10*victim - pieceValues[board.Piece(move.S1()).Type()]
+		}
+		if move.Promo() != chess.NoPieceType {
+			p += 5000 + pieceValues[move.Promo()]
+		}
+		if move.HasTag(chess.Check) {
+			p += 1000
+		}
+		return p
+	}
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return priority(ordered[i]) > priority(ordered[j])
+	})
+	return ordered
+}
+
+func encodeMove(position *chess.Position, move *chess.Move) string {
+	return (chess.UCINotation{}).Encode(position, move)
+}
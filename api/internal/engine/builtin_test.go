@@ -0,0 +1,109 @@
+package engine_test
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/engine"
+	"github.com/style77/stockfish-or-not/internal/enginetest"
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+func TestOpenEngineFallsBack(t *testing.T) {
+	notUCI, err := exec.LookPath("true")
+	if err != nil {
+		t.Skip("no executable to stand in for a non-UCI binary:", err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"missing binary", filepath.Join(t.TempDir(), "stockfish")},
+		{"binary without uci", notUCI},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := engine.OpenEngine(tt.path, 1, nil, random.NewSeeded(1))
+			defer e.Close()
+
+			if _, ok := e.(*engine.BuiltinEngine); !ok {
+				t.Errorf("opened %T, want the built-in engine", e)
+			}
+		})
+	}
+}
+
+func TestOpenEngineStartsUCIEngine(t *testing.T) {
+	e := engine.OpenEngine(enginetest.Path(), 1, nil, random.NewSeeded(1))
+	defer e.Close()
+
+	if _, ok := e.(*engine.EnginePool); !ok {
+		t.Errorf("opened %T, want the engine pool", e)
+	}
+}
+
+func TestBuiltinFindsMateInOne(t *testing.T) {
+	tests := []struct {
+		name     string
+		position engine.Position
+		want     string
+	}{
+		{"back rank", engine.Position{FEN: "6k1/5ppp/8/8/8/8/8/R5K1 w - - 0 1"}, "a1a8"},
+		{"fool's mate", engine.Position{Moves: []string{"f2f3", "e7e5", "g2g4"}}, "d8h4"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := engine.NewBuiltinEngine(random.NewSeeded(1)).Search("game", engine.SearchOptions{}, tt.position, 3)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.BestMove != tt.want {
+				t.Errorf("best move = %s, want %s", result.BestMove, tt.want)
+			}
+			if result.Score.Mate != 1 {
+				t.Errorf("score = %+v, want mate in one", result.Score)
+			}
+		})
+	}
+}
+
+func TestBuiltinPlaysLegalMoveFromFEN(t *testing.T) {
+	fen := "r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3"
+	position := engine.Position{FEN: fen, Moves: []string{"f1c4", "g8f6"}}
+
+	options := []engine.SearchOptions{
+		{MultiPV: 3},
+		{MultiPV: 3, LimitStrength: true, Elo: 400},
+	}
+
+	for _, opts := range options {
+		result, err := engine.NewBuiltinEngine(random.NewSeeded(1)).Search("game", opts, position, 3)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		start, err := chess.FEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		game := chess.NewGame(start, chess.UseNotation(chess.UCINotation{}))
+		for _, move := range position.Moves {
+			if err := game.MoveStr(move); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := game.MoveStr(result.BestMove); err != nil {
+			t.Errorf("%+v played %s: %v", opts, result.BestMove, err)
+		}
+		if len(result.Candidates) == 0 || len(result.Candidates) > 3 {
+			t.Errorf("%+v reported %d candidates, want one to three", opts, len(result.Candidates))
+		}
+	}
+}
@@ -1,31 +1,60 @@
 package engine
 
 import (
-	"math/rand"
+	"log"
+	"math"
+
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+const maxSkillLevel = 20
+
+// Stockfish maps UCI_Elo onto its skill levels over this range, the
+// ratings are calibrated against CCRL 40/4.
+const (
+	skillMinElo = 1320
+	skillMaxElo = 3190
+
+	// assumed strength of an engine searching without any limit
+	fullStrengthElo = 3400
+)
+
+// StrengthMode is how the engine was limited to the AI's rating.
+type StrengthMode string
+
+const (
+	StrengthUCIElo     StrengthMode = "uci_elo"
+	StrengthSkillLevel StrengthMode = "skill_level"
+	StrengthFull       StrengthMode = "full"
+	StrengthFixed      StrengthMode = "fixed" // the persona's engine plays at a known strength
 )
 
-var StockfishSkillElo = map[int]int{
-	0:  800,
-	1:  850,
-	2:  900,
-	3:  1000,
-	4:  1100,
-	5:  1200,
-	6:  1300,
-	7:  1400,
-	8:  1500,
-	9:  1600,
-	10: 1700,
-	11: 1800,
-	12: 1900,
-	13: 2000,
-	14: 2100,
-	15: 2200,
-	16: 2300,
-	17: 2400,
-	18: 2500,
-	19: 2600,
-	20: 3000, // 3000+ Elo for highest level
+// Strength describes how strong an AI plays. The engine searches at
+// EngineElo and the move selector plays down to Elo.
+type Strength struct {
+	Elo        int
+	EngineElo  int
+	Mode       StrengthMode
+	SkillLevel int
+}
+
+// EloRange is the distribution AI ratings are drawn from, uniform between
+// Min and Max when StdDev is zero and otherwise normal around Mean, cut off
+// at Min and Max.
+type EloRange struct {
+	Min    int
+	Max    int
+	Mean   int
+	StdDev int
+}
+
+func (r EloRange) Sample(rnd random.Rand) int {
+	if r.StdDev <= 0 {
+		return r.Min + rnd.IntN(r.Max-r.Min+1)
+	}
+
+	elo := int(math.Round(float64(r.Mean) + rnd.NormFloat64()*float64(r.StdDev)))
+	return min(max(elo, r.Min), r.Max)
 }
 
 func abs(x int) int {
@@ -35,25 +64,79 @@ func abs(x int) int {
 	return x
 }
 
-func getSkillLevel(elo int) int {
-	closestLevel := 0
-	closestDifference := 10000
+// eloSkill is the fractional skill level Stockfish itself plays elo at when
+// UCI_LimitStrength is on.
+func eloSkill(elo int) float64 {
+	e := float64(elo-skillMinElo) / float64(skillMaxElo-skillMinElo)
+	return ((37.2473*e-40.8525)*e+22.2943)*e - 0.311438
+}
 
-	for level, rating := range StockfishSkillElo {
-		difference := abs(rating - elo)
-		if difference < closestDifference {
-			closestDifference = difference
-			closestLevel = level
+// skillLevel rounds the skill level for elo up, so the engine is never
+// weaker than asked for.
+func skillLevel(elo int) int {
+	return min(max(int(math.Ceil(eloSkill(elo))), 0), maxSkillLevel)
+}
+
+// skillLevelElo is the rating Stockfish plays a skill level at.
+func skillLevelElo(level int) int {
+	for elo := skillMinElo; elo <= skillMaxElo; elo++ {
+		if eloSkill(elo) >= float64(level) {
+			return elo
 		}
 	}
-	return closestLevel
+	return fullStrengthElo
+}
+
+// chooseStrength limits the engine as close to elo as the engine's options
+// allow, UCI_Elo is the most accurate and Skill Level the fallback.
+func chooseStrength(options map[string]EngineOption, elo int) Strength {
+	uciElo, hasUCIElo := options["UCI_Elo"]
+	_, hasLimitStrength := options["UCI_LimitStrength"]
+
+	if hasUCIElo && hasLimitStrength && elo <= uciElo.Max {
+		engineElo := max(elo, uciElo.Min)
+		return Strength{Elo: elo, EngineElo: engineElo, Mode: StrengthUCIElo, SkillLevel: maxSkillLevel}
+	}
+
+	if _, ok := options["Skill Level"]; ok {
+		level := skillLevel(elo)
+		return Strength{Elo: elo, EngineElo: skillLevelElo(level), Mode: StrengthSkillLevel, SkillLevel: level}
+	}
+
+	// an engine that can't be limited to elo plays at least at the top of its
+	// UCI_Elo range
+	engineElo := fullStrengthElo
+	if hasUCIElo {
+		engineElo = uciElo.Max
+	}
+
+	return Strength{Elo: elo, EngineElo: engineElo, Mode: StrengthFull, SkillLevel: maxSkillLevel}
 }
 
-func DeterminateAI() (*AIManager, int) {
-	selectedRank := rand.Intn(2000) + 100 // Random Elo between 100 and 2100
-	skillLevel := getSkillLevel(selectedRank)
+// DeterminateAI creates an AI opponent of the persona with a rating drawn
+// from its range. The engine is limited as far as it supports and the human
+// error model plays down the rest of the way. rnd decides the rating and
+// every random choice the AI makes later on.
+func DeterminateAI(e Engine, persona Persona, multiPV int, rnd random.Rand) *AIManager {
+	elo := persona.Elo.Sample(rnd)
 
-	manager := NewAIManager(skillLevel)
+	var strength Strength
+	if persona.EngineElo > 0 {
+		strength = Strength{Elo: elo, EngineElo: persona.EngineElo, Mode: StrengthFixed, SkillLevel: maxSkillLevel}
+	} else {
+		options, err := e.Options()
+		if err != nil {
+			log.Println("Error reading engine options, assuming none:", err)
+		}
+
+		strength = chooseStrength(options, elo)
+	}
 
-	return manager, StockfishSkillElo[skillLevel]
+	return NewAIManager(e, AIOptions{
+		Persona:  persona,
+		Strength: strength,
+		MultiPV:  multiPV,
+		Selector: HumanSelector{Elo: strength.Elo, EngineElo: strength.EngineElo, Rand: rnd},
+		Rand:     rnd,
+	})
 }
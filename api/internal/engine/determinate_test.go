@@ -0,0 +1,53 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/style77/stockfish-or-not/internal/engine"
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+func TestDeterminateAIIsReproducible(t *testing.T) {
+	persona := engine.Persona{
+		Name:   "builtin",
+		Elo:    engine.EloRange{Min: 400, Max: 2400, Mean: 1200, StdDev: 400},
+		Timing: engine.ThinkTimeModel{Min: time.Second, Max: 20 * time.Second},
+	}
+
+	play := func(seed uint64) (engine.Strength, string) {
+		rnd := random.NewSeeded(seed)
+		ai := engine.DeterminateAI(engine.NewBuiltinEngine(rnd), persona, 3, rnd)
+
+		result, err := ai.ProcessMove(engine.Position{}, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ai.Strength(), ai.SelectMove(engine.Choice{Candidates: result.Candidates, BestMove: result.BestMove})
+	}
+
+	for seed := uint64(0); seed < 5; seed++ {
+		strength, move := play(seed)
+		againStrength, againMove := play(seed)
+
+		if strength != againStrength || move != againMove {
+			t.Errorf("seed %d gave %+v playing %s, then %+v playing %s", seed, strength, move, againStrength, againMove)
+		}
+	}
+}
+
+func TestEloRangeStaysInBounds(t *testing.T) {
+	rnd := random.NewSeeded(1)
+	ranges := []engine.EloRange{
+		{Min: 100, Max: 2100},
+		{Min: 800, Max: 1200, Mean: 1000, StdDev: 1000},
+	}
+
+	for _, r := range ranges {
+		for i := 0; i < 1000; i++ {
+			if elo := r.Sample(rnd); elo < r.Min || elo > r.Max {
+				t.Fatalf("%+v sampled %d", r, elo)
+			}
+		}
+	}
+}
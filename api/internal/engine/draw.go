@@ -0,0 +1,41 @@
+package engine
+
+const (
+	// evaluation depth for draw offers, deep enough not to misjudge simple
+	// tactics but quick enough to answer like a human would
+	drawEvalDepth = 12
+
+	// positions the AI considers lost are drawn whenever possible
+	drawLosingScore = -150
+
+	// in the opening humans rarely agree to a draw, even in equal positions
+	drawMinPlies = 30
+)
+
+// AcceptsDraw decides whether the AI takes a draw offer in the position.
+// aiToMove tells whose turn it is, as engine scores are relative to the side
+// to move.
+func (m *AIManager) AcceptsDraw(position Position, plies int, aiToMove bool) (bool, error) {
+	score, err := m.Evaluate(position, drawEvalDepth)
+	if err != nil {
+		return false, err
+	}
+
+	if !aiToMove {
+		score = Score{Centipawns: -score.Centipawns, Mate: -score.Mate}
+	}
+
+	switch {
+	case score.Mate > 0:
+		return false, nil
+	case score.Mate < 0:
+		return true, nil
+	case score.Centipawns <= drawLosingScore:
+		return true, nil
+	case plies < drawMinPlies:
+		return false, nil
+	}
+
+	// roughly equal positions are accepted, how equal is up to the mood
+	return score.Centipawns <= m.rand.IntN(60), nil
+}
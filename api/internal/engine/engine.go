@@ -0,0 +1,66 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+// Engine searches positions for AI opponents. Engines are shared by all
+// games, gameID tells searches of different games apart.
+type Engine interface {
+	// Options lists the options the engine supports, strength limits are
+	// picked from them.
+	Options() (map[string]EngineOption, error)
+	Search(gameID string, options SearchOptions, position Position, depth int) (*SearchResult, error)
+	Close()
+}
+
+// Persona is a kind of AI opponent: an engine with its own options, rating
+// range and way of spending time.
+type Persona struct {
+	Name    string
+	Engine  string            // name the engine is registered under
+	Options map[string]string // engine options set for the persona's searches
+	Elo     EloRange
+	// EngineElo is the strength of an engine that isn't limited by options,
+	// e.g. one playing with Maia weights. Zero lets the engine's options
+	// limit it to the persona's rating.
+	EngineElo int
+	Timing    ThinkTimeModel
+}
+
+// Registry holds the engines and the personas AI opponents are picked from.
+type Registry struct {
+	engines  map[string]Engine
+	personas []Persona
+}
+
+func NewRegistry() *Registry {
+	return &Registry{engines: map[string]Engine{}}
+}
+
+func (r *Registry) AddEngine(name string, e Engine) {
+	r.engines[name] = e
+}
+
+func (r *Registry) AddPersona(persona Persona) error {
+	if _, ok := r.engines[persona.Engine]; !ok {
+		return fmt.Errorf("persona %q uses unknown engine %q", persona.Name, persona.Engine)
+	}
+
+	r.personas = append(r.personas, persona)
+	return nil
+}
+
+// NewAI creates an AI opponent from a random persona.
+func (r *Registry) NewAI(multiPV int, rnd random.Rand) (*AIManager, Persona) {
+	persona := r.personas[rnd.IntN(len(r.personas))]
+	return DeterminateAI(r.engines[persona.Engine], persona, multiPV, rnd), persona
+}
+
+func (r *Registry) Close() {
+	for _, e := range r.engines {
+		e.Close()
+	}
+}
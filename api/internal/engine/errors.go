@@ -0,0 +1,23 @@
+package engine
+
+import "errors"
+
+var (
+	ErrEngineExited = errors.New("engine process exited")
+	ErrNoMove       = errors.New("engine returned no move")
+)
+
+// EngineError is returned for every failure talking to an engine process,
+// Op tells which step of the conversation failed.
+type EngineError struct {
+	Op  string
+	Err error
+}
+
+func (e *EngineError) Error() string {
+	return "engine " + e.Op + ": " + e.Err.Error()
+}
+
+func (e *EngineError) Unwrap() error {
+	return e.Err
+}
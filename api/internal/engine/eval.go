@@ -0,0 +1,131 @@
+package engine
+
+import (
+	"github.com/notnil/chess"
+)
+
+var pieceValues = map[chess.PieceType]int{
+	chess.Pawn:   100,
+	chess.Knight: 320,
+	chess.Bishop: 330,
+	chess.Rook:   500,
+	chess.Queen:  900,
+	chess.King:   0,
+}
+
+// piece-square tables from white's point of view, a1 first. Black pieces
+// read them mirrored.
+var pieceSquares = map[chess.PieceType][64]int{
+	chess.Pawn: {
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 10, 10, -20, -20, 10, 10, 5,
+		5, -5, -10, 0, 0, -10, -5, 5,
+		0, 0, 0, 20, 20, 0, 0, 0,
+		5, 5, 10, 25, 25, 10, 5, 5,
+		10, 10, 20, 30, 30, 20, 10, 10,
+		50, 50, 50, 50, 50, 50, 50, 50,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	chess.Knight: {
+		-50, -40, -30, -30, -30, -30, -40, -50,
+		-40, -20, 0, 5, 5, 0, -20, -40,
+		-30, 5, 10, 15, 15, 10, 5, -30,
+		-30, 0, 15, 20, 20, 15, 0, -30,
+		-30, 5, 15, 20, 20, 15, 5, -30,
+		-30, 0, 10, 15, 15, 10, 0, -30,
+		-40, -20, 0, 0, 0, 0, -20, -40,
+		-50, -40, -30, -30, -30, -30, -40, -50,
+	},
+	chess.Bishop: {
+		-20, -10, -10, -10, -10, -10, -10, -20,
+		-10, 5, 0, 0, 0, 0, 5, -10,
+		-10, 10, 10, 10, 10, 10, 10, -10,
+		-10, 0, 10, 10, 10, 10, 0, -10,
+		-10, 5, 5, 10, 10, 5, 5, -10,
+		-10, 0, 5, 10, 10, 5, 0, -10,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-20, -10, -10, -10, -10, -10, -10, -20,
+	},
+	chess.Rook: {
+		0, 0, 0, 5, 5, 0, 0, 0,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		-5, 0, 0, 0, 0, 0, 0, -5,
+		5, 10, 10, 10, 10, 10, 10, 5,
+		0, 0, 0, 0, 0, 0, 0, 0,
+	},
+	chess.Queen: {
+		-20, -10, -10, -5, -5, -10, -10, -20,
+		-10, 0, 5, 0, 0, 0, 0, -10,
+		-10, 5, 5, 5, 5, 5, 0, -10,
+		0, 0, 5, 5, 5, 5, 0, -5,
+		-5, 0, 5, 5, 5, 5, 0, -5,
+		-10, 0, 5, 5, 5, 5, 0, -10,
+		-10, 0, 0, 0, 0, 0, 0, -10,
+		-20, -10, -10, -5, -5, -10, -10, -20,
+	},
+	chess.King: {
+		20, 30, 10, 0, 0, 10, 30, 20,
+		20, 20, 0, 0, 0, 0, 20, 20,
+		-10, -20, -20, -20, -20, -20, -20, -10,
+		-20, -30, -30, -40, -40, -30, -30, -20,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+		-30, -40, -40, -50, -50, -40, -40, -30,
+	},
+}
+
+// the king belongs in the centre once the queens are off
+var kingEndgameSquares = [64]int{
+	-50, -30, -30, -30, -30, -30, -30, -50,
+	-30, -30, 0, 0, 0, 0, -30, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 30, 40, 40, 30, -10, -30,
+	-30, -10, 20, 30, 30, 20, -10, -30,
+	-30, -20, -10, 0, 0, -10, -20, -30,
+	-50, -40, -30, -20, -20, -30, -40, -50,
+}
+
+// evaluate scores the position in centipawns from the point of view of the
+// side to move: material and piece placement, nothing more.
+func evaluate(position *chess.Position) int {
+	squares := position.Board().SquareMap()
+
+	endgame := true
+	for _, piece := range squares {
+		if piece.Type() == chess.Queen {
+			endgame = false
+			break
+		}
+	}
+
+	score := 0
+	for square, piece := range squares {
+		index := int(square)
+		if piece.Color() == chess.Black {
+			index ^= 56 // mirror the rank
+		}
+
+		value := pieceValues[piece.Type()]
+		if piece.Type() == chess.King && endgame {
+			value += kingEndgameSquares[index]
+		} else {
+			value += pieceSquares[piece.Type()][index]
+		}
+
+		if piece.Color() == chess.White {
+			score += value
+		} else {
+			score -= value
+		}
+	}
+
+	if position.Turn() == chess.Black {
+		return -score
+	}
+	return score
+}
@@ -1,48 +1,111 @@
 package engine
 
 import (
-	"log"
 	"sync"
+	"time"
 
-	"github.com/freeeve/uci"
+	"github.com/google/uuid"
+	"github.com/style77/stockfish-or-not/internal/random"
 )
 
+// AIOptions configure how an AI opponent searches, picks and times its moves.
+type AIOptions struct {
+	Persona  Persona // engine options and think times
+	Strength Strength
+	MultiPV  int // candidate lines the selector picks from
+	Selector MoveSelector
+	Rand     random.Rand // nil draws from the global source
+}
+
 type AIManager struct {
-	engine *uci.Engine
-	mux    sync.Mutex
+	engine    Engine
+	gameID    string
+	persona   Persona
+	strength  Strength
+	multiPV   int
+	selector  MoveSelector
+	rand      random.Rand
+	lastScore *Score // evaluation at the AI's previous move
+	mux       sync.Mutex
 }
 
-func NewAIManager(skillLevel int) *AIManager {
-	engine, err := uci.NewEngine("../stockfish")
-	if err != nil {
-		log.Fatal("Error creating engine:", err)
+func NewAIManager(e Engine, options AIOptions) *AIManager {
+	selector := options.Selector
+	if selector == nil {
+		selector = BestMoveSelector{}
+	}
+
+	rnd := options.Rand
+	if rnd == nil {
+		rnd = random.Default()
+	}
+
+	return &AIManager{
+		engine:   e,
+		gameID:   uuid.New().String(),
+		persona:  options.Persona,
+		strength: options.Strength,
+		multiPV:  options.MultiPV,
+		selector: selector,
+		rand:     rnd,
 	}
+}
+
+func (m *AIManager) Persona() Persona {
+	return m.persona
+}
 
-	engine.SendOption("Skill Level", skillLevel)
-	return &AIManager{engine: engine}
+func (m *AIManager) Strength() Strength {
+	return m.strength
 }
 
-func (m *AIManager) ProcessMove(position string, depth int) (string, error) {
+func (m *AIManager) ProcessMove(position Position, depth int) (*SearchResult, error) {
 	m.mux.Lock()
 	defer m.mux.Unlock()
 
-	err := m.engine.SetMoves(position)
-	if err != nil {
-		log.Fatal("Error setting moves:", err)
-		return "", err
+	return m.engine.Search(m.gameID, SearchOptions{
+		SkillLevel:    m.strength.SkillLevel,
+		LimitStrength: m.strength.Mode == StrengthUCIElo,
+		Elo:           m.strength.EngineElo,
+		MultiPV:       m.multiPV,
+		Extra:         m.persona.Options,
+	}, position, depth)
+}
+
+// SelectMove picks the move to play among the searched candidates.
+func (m *AIManager) SelectMove(choice Choice) string {
+	if len(choice.Candidates) == 0 {
+		return ""
 	}
 
-	results, err := m.engine.Go(depth, "", 0)
+	return m.selector.Select(choice).Move
+}
+
+// Evaluate scores the position at full strength, from the point of view of
+// the side to move.
+func (m *AIManager) Evaluate(position Position, depth int) (Score, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	result, err := m.engine.Search(m.gameID, SearchOptions{SkillLevel: maxSkillLevel, Extra: m.persona.Options}, position, depth)
 	if err != nil {
-		log.Fatal("Error getting best move:", err)
-		return "", err
+		return Score{}, err
 	}
 
-	bestMove := results.BestMove
-
-	return bestMove, nil
+	return result.Score, nil
 }
 
-func (m *AIManager) Close() {
-	m.engine.Close()
+// ThinkTime decides how long to wait before playing the searched move, the
+// search's evaluation is remembered to notice swings on the next move.
+func (m *AIManager) ThinkTime(ctx MoveContext, result *SearchResult) time.Duration {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	swing := 0
+	if m.lastScore != nil {
+		swing = abs(result.Score.centipawns() - m.lastScore.centipawns())
+	}
+	m.lastScore = &result.Score
+
+	return m.persona.Timing.ThinkTime(ctx, swing, m.rand)
 }
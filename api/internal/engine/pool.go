@@ -0,0 +1,163 @@
+package engine
+
+import (
+	"log"
+	"sync"
+)
+
+// SearchOptions are sent to the engine before every search, engines are
+// shared between games so nothing can be assumed about their previous state.
+type SearchOptions struct {
+	SkillLevel    int
+	LimitStrength bool
+	Elo           int // only used with LimitStrength
+	MultiPV       int // number of candidate lines to report, at least one
+	// Extra options of the AI's persona, options a previous search set but
+	// this one doesn't are reset to their defaults
+	Extra map[string]string
+}
+
+// EnginePool is the Engine for any UCI binary, it keeps a fixed number of
+// long-lived processes. Processes are started lazily and replaced whenever
+// they stop responding.
+type EnginePool struct {
+	path    string
+	setup   map[string]string // options set once when a process starts, e.g. weights
+	engines chan *uciEngine   // nil means the slot has no running process
+
+	options    map[string]EngineOption // announced by the first started engine
+	optionsMux sync.Mutex
+}
+
+func NewEnginePool(path string, size int, setup map[string]string) *EnginePool {
+	pool := &EnginePool{
+		path:    path,
+		setup:   setup,
+		engines: make(chan *uciEngine, size),
+	}
+
+	for i := 0; i < size; i++ {
+		pool.engines <- nil
+	}
+
+	return pool
+}
+
+func (p *EnginePool) acquire() (*uciEngine, error) {
+	e := <-p.engines
+
+	if e != nil {
+		err := e.isReady(readyTimeout)
+		if err == nil {
+			return e, nil
+		}
+
+		log.Println("Engine is not responding, restarting it:", err)
+		e.close()
+	}
+
+	e, err := startUCIEngine(p.path, p.setup)
+	if err != nil {
+		p.engines <- nil
+		return nil, &EngineError{Op: "start", Err: err}
+	}
+
+	p.optionsMux.Lock()
+	if p.options == nil {
+		p.options = e.options
+	}
+	p.optionsMux.Unlock()
+
+	return e, nil
+}
+
+// Options returns the options the engine supports, starting an engine if
+// none ran yet. All engines in the pool run the same binary.
+func (p *EnginePool) Options() (map[string]EngineOption, error) {
+	p.optionsMux.Lock()
+	options := p.options
+	p.optionsMux.Unlock()
+
+	if options != nil {
+		return options, nil
+	}
+
+	e, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+	p.release(e, true)
+
+	return e.options, nil
+}
+
+func (p *EnginePool) release(e *uciEngine, healthy bool) {
+	if !healthy {
+		e.close()
+		e = nil
+	}
+
+	p.engines <- e
+}
+
+// Search borrows an engine for a single search. Switching the engine to a
+// different game starts a new game on it first.
+func (p *EnginePool) Search(gameID string, options SearchOptions, position Position, depth int) (*SearchResult, error) {
+	e, err := p.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := p.search(e, gameID, options, position, depth)
+	p.release(e, err == nil)
+
+	if err != nil {
+		return nil, &EngineError{Op: "search", Err: err}
+	}
+
+	return result, nil
+}
+
+func (p *EnginePool) search(e *uciEngine, gameID string, options SearchOptions, position Position, depth int) (*SearchResult, error) {
+	if e.gameID != gameID {
+		if err := e.newGame(gameID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.resetExtraOptions(options.Extra); err != nil {
+		return nil, err
+	}
+
+	if err := e.setOption("Skill Level", options.SkillLevel); err != nil {
+		return nil, err
+	}
+
+	if err := e.setOption("UCI_LimitStrength", options.LimitStrength); err != nil {
+		return nil, err
+	}
+
+	if options.LimitStrength {
+		if err := e.setOption("UCI_Elo", options.Elo); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.setOption("MultiPV", max(options.MultiPV, 1)); err != nil {
+		return nil, err
+	}
+
+	if err := e.setExtraOptions(options.Extra); err != nil {
+		return nil, err
+	}
+
+	return e.search(position, depth)
+}
+
+func (p *EnginePool) Close() {
+	for i := 0; i < cap(p.engines); i++ {
+		if e := <-p.engines; e != nil {
+			e.close()
+		}
+	}
+}
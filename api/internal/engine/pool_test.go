@@ -0,0 +1,83 @@
+package engine_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/style77/stockfish-or-not/internal/engine"
+	"github.com/style77/stockfish-or-not/internal/enginetest"
+)
+
+func TestMain(m *testing.M) {
+	enginetest.RunIfFakeUCI()
+	os.Exit(m.Run())
+}
+
+func TestSearchPlaysScriptedMove(t *testing.T) {
+	pool := engine.NewEnginePool(enginetest.Path("e2e4", "e7e5", "g1f3"), 1, nil)
+	defer pool.Close()
+
+	result, err := pool.Search("game", engine.SearchOptions{MultiPV: 3}, engine.Position{Moves: []string{"e2e4", "e7e5"}}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.BestMove != "g1f3" {
+		t.Errorf("best move = %s, want g1f3", result.BestMove)
+	}
+	if len(result.Candidates) != 1 || result.Candidates[0].Move != "g1f3" {
+		t.Errorf("candidates = %v, want only g1f3", result.Candidates)
+	}
+}
+
+func TestSearchFromFEN(t *testing.T) {
+	pool := engine.NewEnginePool(enginetest.Path("e2e4", "e7e5", "g1f3", "b8c6"), 1, nil)
+	defer pool.Close()
+
+	position := engine.Position{
+		FEN:   "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq - 0 2",
+		Moves: []string{"g1f3"},
+	}
+
+	result, err := pool.Search("game", engine.SearchOptions{}, position, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.BestMove != "b8c6" {
+		t.Errorf("best move = %s, want b8c6", result.BestMove)
+	}
+}
+
+func TestSearchWithoutLegalMoves(t *testing.T) {
+	pool := engine.NewEnginePool(enginetest.Path(), 1, nil)
+	defer pool.Close()
+
+	// white is checkmated
+	position := engine.Position{FEN: "rnb1kbnr/pppp1ppp/8/4p3/6Pq/5P2/PPPPP2P/RNBQKBNR w KQkq - 1 3"}
+
+	if _, err := pool.Search("game", engine.SearchOptions{}, position, 5); err == nil {
+		t.Error("search of a mated position succeeded")
+	}
+}
+
+func TestOptions(t *testing.T) {
+	pool := engine.NewEnginePool(enginetest.Path(), 1, nil)
+	defer pool.Close()
+
+	options, err := pool.Options()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	elo, ok := options["UCI_Elo"]
+	if !ok {
+		t.Fatal("UCI_Elo was not announced")
+	}
+	if elo.Min != 1320 || elo.Max != 3190 {
+		t.Errorf("UCI_Elo range = %d-%d, want 1320-3190", elo.Min, elo.Max)
+	}
+	if _, ok := options["Skill Level"]; !ok {
+		t.Error("Skill Level was not announced")
+	}
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"math"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+// Choice is what a MoveSelector picks the AI's move from.
+type Choice struct {
+	Position   *chess.Position // position the move is played in
+	Candidates []Candidate     // engine lines, best first
+	BestMove   string          // the engine's own pick, weakened by its strength settings
+	Remaining  time.Duration   // time left on the AI's clock
+}
+
+// MoveSelector decides which of the engine's candidates the AI plays.
+type MoveSelector interface {
+	Select(choice Choice) Candidate
+}
+
+// BestMoveSelector always plays the engine's own pick, strength is left to
+// the engine's settings.
+type BestMoveSelector struct{}
+
+func (BestMoveSelector) Select(choice Choice) Candidate {
+	for _, candidate := range choice.Candidates {
+		if candidate.Move == choice.BestMove {
+			return candidate
+		}
+	}
+	return choice.Candidates[0]
+}
+
+const (
+	// temperatures (in centipawns) of the error model are scaled from this
+	// one at 800 Elo, falling off by a factor of e every temperatureFalloff
+	baseTemperature    = 180
+	temperatureFalloff = 700
+	minTemperature     = 5
+
+	// chance of an outright blunder at 800 Elo, falling off by a factor of e
+	// every blunderFalloff
+	baseBlunderChance = 0.08
+	blunderFalloff    = 500
+	blunderLoss       = 150 // centipawns a move must lose to be a blunder
+
+	// captures and checks are the moves humans look at first
+	naturalBonus = 30
+
+	timeTroubleSevere = 10 * time.Second
+	timeTrouble       = 30 * time.Second
+)
+
+// HumanSelector plays like a human of the given Elo: it samples the
+// candidates by how much they lose against the best one, weighted by a
+// temperature that grows as the rating drops. It occasionally blunders,
+// more so in time trouble, and is drawn to captures and checks.
+//
+// EngineElo is the strength the engine searched at, the error model only
+// makes up the difference. The engine's own pick counts as losing nothing,
+// so an engine already limited to Elo is hardly weakened any further.
+type HumanSelector struct {
+	Elo       int
+	EngineElo int
+	Rand      random.Rand // nil draws from the global source
+}
+
+func temperature(elo int) float64 {
+	return baseTemperature * math.Exp(-float64(elo-800)/temperatureFalloff)
+}
+
+func blunderChance(elo int) float64 {
+	return baseBlunderChance * math.Exp(-float64(elo-800)/blunderFalloff)
+}
+
+func (s HumanSelector) Select(choice Choice) Candidate {
+	rnd := s.Rand
+	if rnd == nil {
+		rnd = random.Default()
+	}
+
+	candidates := withBestMove(choice)
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+
+	t := max(temperature(s.Elo)-temperature(s.EngineElo), minTemperature)
+	chance := max(blunderChance(s.Elo)-blunderChance(s.EngineElo), 0)
+
+	switch {
+	case choice.Remaining > 0 && choice.Remaining < timeTroubleSevere:
+		t *= 2
+		chance *= 3
+	case choice.Remaining > 0 && choice.Remaining < timeTrouble:
+		t *= 1.4
+		chance *= 1.5
+	}
+
+	best := candidates[0].Score.centipawns()
+
+	if rnd.Float64() < chance {
+		var blunders []Candidate
+		for _, candidate := range candidates {
+			if best-candidate.Score.centipawns() >= blunderLoss {
+				blunders = append(blunders, candidate)
+			}
+		}
+
+		if len(blunders) > 0 {
+			return blunders[rnd.IntN(len(blunders))]
+		}
+	}
+
+	natural := naturalMoves(choice.Position)
+
+	weights := make([]float64, len(candidates))
+	total := 0.0
+	for i, candidate := range candidates {
+		loss := float64(best - candidate.Score.centipawns())
+		if candidate.Move == choice.BestMove {
+			loss = 0
+		}
+		if natural[candidate.Move] {
+			loss -= naturalBonus
+		}
+
+		weights[i] = math.Exp(-max(loss, 0) / t)
+		total += weights[i]
+	}
+
+	pick := rnd.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return candidates[i]
+		}
+		pick -= weight
+	}
+
+	return candidates[0]
+}
+
+// withBestMove returns the candidates with the engine's own pick among them,
+// an engine limiting its strength may pick a move outside its reported lines.
+// Such a move is ranked with the top line.
+func withBestMove(choice Choice) []Candidate {
+	if choice.BestMove == "" {
+		return choice.Candidates
+	}
+
+	for _, candidate := range choice.Candidates {
+		if candidate.Move == choice.BestMove {
+			return choice.Candidates
+		}
+	}
+
+	best := Candidate{Move: choice.BestMove, Score: choice.Candidates[0].Score}
+	return append([]Candidate{best}, choice.Candidates...)
+}
+
+// naturalMoves lists the captures and checks in the position.
+func naturalMoves(position *chess.Position) map[string]bool {
+	natural := map[string]bool{}
+	if position == nil {
+		return natural
+	}
+
+	for _, move := range position.ValidMoves() {
+		if move.HasTag(chess.Capture) || move.HasTag(chess.Check) {
+			natural[(chess.UCINotation{}).Encode(position, move)] = true
+		}
+	}
+
+	return natural
+}
@@ -0,0 +1,84 @@
+package engine_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/style77/stockfish-or-not/internal/engine"
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+// lines are candidates from the start position, a2a3 throws the game away.
+var lines = []engine.Candidate{
+	{Move: "e2e4", Score: engine.Score{Centipawns: 30}},
+	{Move: "d2d4", Score: engine.Score{Centipawns: 20}},
+	{Move: "g1f3", Score: engine.Score{Centipawns: -20}},
+	{Move: "a2a3", Score: engine.Score{Centipawns: -300}},
+}
+
+// picks counts how often the selector plays each move.
+func picks(selector engine.HumanSelector, choice engine.Choice, draws int) map[string]int {
+	counts := map[string]int{}
+	for i := 0; i < draws; i++ {
+		counts[selector.Select(choice).Move]++
+	}
+	return counts
+}
+
+func TestHumanSelectorTemperature(t *testing.T) {
+	choice := engine.Choice{Candidates: lines, BestMove: "e2e4"}
+
+	// an engine already playing at the player's strength leaves the least
+	// room for error
+	strong := picks(engine.HumanSelector{Elo: 3000, EngineElo: 3000, Rand: random.NewSeeded(1)}, choice, 1000)
+	if strong["e2e4"] < 800 || strong["a2a3"] > 0 {
+		t.Errorf("a strong player played the top line %d times out of 1000, picks %v", strong["e2e4"], strong)
+	}
+
+	weak := picks(engine.HumanSelector{Elo: 800, EngineElo: 3000, Rand: random.NewSeeded(1)}, choice, 1000)
+	if weak["e2e4"] > 600 {
+		t.Errorf("a weak player played the top line %d times out of 1000, picks %v", weak["e2e4"], weak)
+	}
+	if weak["d2d4"] <= weak["g1f3"] {
+		t.Errorf("a weak player preferred the worse move, picks %v", weak)
+	}
+}
+
+func TestHumanSelectorBlunders(t *testing.T) {
+	// far enough below 800 Elo and in time trouble every move is a blunder
+	selector := engine.HumanSelector{Elo: 0, EngineElo: 3000, Rand: random.NewSeeded(1)}
+	choice := engine.Choice{Candidates: lines, BestMove: "e2e4", Remaining: 5 * time.Second}
+
+	if counts := picks(selector, choice, 100); counts["a2a3"] != 100 {
+		t.Errorf("blundered %d times out of 100, picks %v", counts["a2a3"], counts)
+	}
+
+	// without a move losing enough there is nothing to blunder
+	choice.Candidates = lines[:3]
+	if counts := picks(selector, choice, 100); counts["a2a3"] != 0 {
+		t.Errorf("played a move that isn't a candidate, picks %v", counts)
+	}
+}
+
+func TestHumanSelectorPlaysBestMoveOutsideLines(t *testing.T) {
+	// an engine limiting its strength picked a move it didn't report a line for
+	selector := engine.HumanSelector{Elo: 3000, EngineElo: 3000, Rand: random.NewSeeded(1)}
+	choice := engine.Choice{Candidates: lines, BestMove: "h2h3"}
+
+	counts := picks(selector, choice, 1000)
+	if counts["h2h3"] < 400 {
+		t.Errorf("played the engine's pick %d times out of 1000, picks %v", counts["h2h3"], counts)
+	}
+	if counts["a2a3"] > 0 {
+		t.Errorf("blundered without a reason, picks %v", counts)
+	}
+
+	// the engine's pick is ranked with the top line
+	single := engine.Choice{Candidates: lines[:1], BestMove: "h2h3"}
+	for i := 0; i < 100; i++ {
+		picked := selector.Select(single)
+		if picked.Move != "e2e4" && picked.Move != "h2h3" || picked.Score != lines[0].Score {
+			t.Fatalf("picked %+v, want the top line or the engine's pick scored like it", picked)
+		}
+	}
+}
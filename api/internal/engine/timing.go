@@ -0,0 +1,104 @@
+package engine
+
+import (
+	"math"
+	"time"
+
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+// MoveContext describes the situation the AI is moving in, as far as it
+// changes how long a human would think about the move.
+type MoveContext struct {
+	Remaining  time.Duration // time left on the AI's clock
+	Increment  time.Duration
+	MoveNumber int // full move number
+	LegalMoves int
+	Pieces     int  // knights, bishops, rooks and queens of both colors
+	Recapture  bool // the move takes back on the square just captured on
+}
+
+// ThinkTimeModel picks how long the AI waits before playing a move. Times
+// follow a log-normal distribution, like human move times do, around a share
+// of the remaining clock that is scaled by the position.
+type ThinkTimeModel struct {
+	Min time.Duration
+	Max time.Duration
+}
+
+const (
+	// spread of the log-normal distribution, higher values give more very
+	// quick and very slow moves
+	thinkTimeSigma = 0.6
+
+	// a game is assumed to last at least this many more moves, so the clock
+	// isn't spent too early
+	minMovesLeft = 15
+	movesPerGame = 40
+
+	// swings in evaluation below this many centipawns go unnoticed
+	swingThreshold = 100
+)
+
+// ThinkTime returns how long the AI thinks about its move. swing is the
+// change in the engine's evaluation since the AI's previous move, in
+// centipawns.
+func (m ThinkTimeModel) ThinkTime(ctx MoveContext, swing int, rnd random.Rand) time.Duration {
+	movesLeft := max(movesPerGame-ctx.MoveNumber, minMovesLeft)
+	budget := float64(ctx.Remaining/time.Duration(movesLeft) + ctx.Increment*3/4)
+
+	factor := 1.0
+
+	switch {
+	case ctx.MoveNumber <= 8 && ctx.Pieces >= 12:
+		// opening moves are played from memory
+		factor *= 0.4
+	case ctx.Pieces <= 4:
+		factor *= 0.8
+	default:
+		// the middlegame is where humans spend their time
+		factor *= 1.3
+	}
+
+	switch {
+	case ctx.LegalMoves == 1:
+		factor *= 0.15
+	case ctx.LegalMoves <= 4:
+		factor *= 0.6
+	case ctx.LegalMoves >= 35:
+		factor *= 1.2
+	}
+
+	if ctx.Recapture {
+		factor *= 0.3
+	}
+
+	// a position that went differently than expected needs a rethink
+	if swing >= swingThreshold {
+		factor *= 1 + float64(min(swing, 4*swingThreshold))/(2*swingThreshold)
+	}
+
+	// the -sigma²/2 keeps the mean of the noise at one
+	noise := math.Exp(rnd.NormFloat64()*thinkTimeSigma - thinkTimeSigma*thinkTimeSigma/2)
+	thinkTime := time.Duration(budget * factor * noise)
+
+	// never spend more than a quarter of the clock, flagging gives a bot away
+	limit := min(m.Max, ctx.Remaining/4)
+	if limit < m.Min {
+		return limit
+	}
+
+	return min(max(thinkTime, m.Min), limit)
+}
+
+// centipawns flattens a score for comparing evaluations, mates count as a
+// decisive advantage.
+func (s Score) centipawns() int {
+	switch {
+	case s.Mate > 0:
+		return 10000
+	case s.Mate < 0:
+		return -10000
+	}
+	return s.Centipawns
+}
@@ -0,0 +1,92 @@
+package engine_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/style77/stockfish-or-not/internal/engine"
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+// middlegame is a position with enough choice that no factor but the
+// middlegame's applies.
+var middlegame = engine.MoveContext{Remaining: 100 * time.Hour, MoveNumber: 20, LegalMoves: 20, Pieces: 10}
+
+func TestThinkTimeClamps(t *testing.T) {
+	tests := []struct {
+		name  string
+		model engine.ThinkTimeModel
+		ctx   engine.MoveContext
+		want  time.Duration
+	}{
+		{
+			name:  "quick move raised to min",
+			model: engine.ThinkTimeModel{Min: 30 * time.Second, Max: time.Hour},
+			ctx:   engine.MoveContext{Remaining: time.Hour, MoveNumber: 1, LegalMoves: 1, Pieces: 14, Recapture: true},
+			want:  30 * time.Second,
+		},
+		{
+			name:  "long think cut to max",
+			model: engine.ThinkTimeModel{Min: time.Second, Max: 10 * time.Second},
+			ctx:   engine.MoveContext{Remaining: 100 * time.Hour, MoveNumber: 20, LegalMoves: 35, Pieces: 10},
+			want:  10 * time.Second,
+		},
+		{
+			name:  "cut to a quarter of the clock",
+			model: engine.ThinkTimeModel{Max: time.Minute},
+			ctx:   engine.MoveContext{Remaining: 8 * time.Second, Increment: time.Minute, MoveNumber: 20, LegalMoves: 20, Pieces: 10},
+			want:  2 * time.Second,
+		},
+		{
+			name:  "quarter of the clock below min",
+			model: engine.ThinkTimeModel{Min: time.Second, Max: time.Minute},
+			ctx:   engine.MoveContext{Remaining: 2 * time.Second, MoveNumber: 20, LegalMoves: 20, Pieces: 10},
+			want:  500 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rnd := random.NewSeeded(1)
+			for i := 0; i < 100; i++ {
+				if got := tt.model.ThinkTime(tt.ctx, 0, rnd); got != tt.want {
+					t.Fatalf("draw %d thought %s, want %s", i, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestThinkTimeFactors(t *testing.T) {
+	model := engine.ThinkTimeModel{Max: 1000 * time.Hour}
+
+	forced := middlegame
+	forced.LegalMoves = 1
+
+	recapture := middlegame
+	recapture.Recapture = true
+
+	tests := []struct {
+		name   string
+		ctx    engine.MoveContext
+		factor float64
+	}{
+		{"forced move", forced, 0.15},
+		{"recapture", recapture, 0.3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for seed := uint64(0); seed < 10; seed++ {
+				// the same seed draws the same noise for both moves
+				base := model.ThinkTime(middlegame, 0, random.NewSeeded(seed))
+				got := model.ThinkTime(tt.ctx, 0, random.NewSeeded(seed))
+
+				if ratio := float64(got) / float64(base); math.Abs(ratio-tt.factor) > 1e-6 {
+					t.Errorf("seed %d thought %s against %s, a factor of %.4f, want %.2f", seed, got, base, ratio, tt.factor)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,400 @@
+package engine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	startTimeout  = 10 * time.Second
+	readyTimeout  = 2 * time.Second
+	searchTimeout = 60 * time.Second
+)
+
+// uciEngine is a single running UCI process. It is not safe for concurrent
+// use, the pool hands every engine to one search at a time.
+type uciEngine struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string
+
+	gameID  string // game the engine was last searching for
+	options map[string]EngineOption
+	extra   map[string]string // persona options of the last search
+}
+
+// EngineOption is an option the engine announced in its uci answer. Min and
+// Max are only set for spin options.
+type EngineOption struct {
+	Type    string
+	Default string
+	Min     int
+	Max     int
+}
+
+// parseOption reads an "option name <name> type <type> ..." line, names may
+// contain spaces.
+func parseOption(line string) (string, EngineOption, bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 5 || fields[0] != "option" || fields[1] != "name" {
+		return "", EngineOption{}, false
+	}
+
+	var name []string
+	i := 2
+	for ; i < len(fields) && fields[i] != "type"; i++ {
+		name = append(name, fields[i])
+	}
+
+	if len(name) == 0 || i+1 >= len(fields) {
+		return "", EngineOption{}, false
+	}
+
+	option := EngineOption{Type: fields[i+1]}
+	for i += 2; i+1 < len(fields); i += 2 {
+		switch fields[i] {
+		case "default":
+			option.Default = fields[i+1]
+		case "min":
+			option.Min, _ = strconv.Atoi(fields[i+1])
+		case "max":
+			option.Max, _ = strconv.Atoi(fields[i+1])
+		}
+	}
+
+	return strings.Join(name, " "), option, true
+}
+
+// startUCIEngine runs the binary at path and sets the setup options before
+// the engine is used.
+func startUCIEngine(path string, setup map[string]string) (*uciEngine, error) {
+	cmd := exec.Command(path)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	e := &uciEngine{cmd: cmd, stdin: stdin, lines: make(chan string, 64), options: map[string]EngineOption{}}
+	go e.read(stdout)
+
+	if err := e.send("uci"); err != nil {
+		e.close()
+		return nil, err
+	}
+
+	_, err = e.readUntil("uciok", startTimeout, func(line string) {
+		if name, option, ok := parseOption(line); ok {
+			e.options[name] = option
+		}
+	})
+	if err != nil {
+		e.close()
+		return nil, err
+	}
+
+	for name, value := range setup {
+		if _, ok := e.options[name]; !ok {
+			log.Println("Engine", path, "does not support option", name)
+		}
+
+		if err := e.setOption(name, value); err != nil {
+			e.close()
+			return nil, err
+		}
+	}
+
+	if err := e.isReady(startTimeout); err != nil {
+		e.close()
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (e *uciEngine) read(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		e.lines <- scanner.Text()
+	}
+	close(e.lines)
+}
+
+func (e *uciEngine) send(command string) error {
+	_, err := io.WriteString(e.stdin, command+"\n")
+	return err
+}
+
+// readUntil consumes engine output until a line starting with prefix shows
+// up, passing every other line to onLine.
+func (e *uciEngine) readUntil(prefix string, timeout time.Duration, onLine func(string)) (string, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case line, ok := <-e.lines:
+			if !ok {
+				return "", ErrEngineExited
+			}
+
+			if strings.HasPrefix(line, prefix) {
+				return line, nil
+			}
+
+			if onLine != nil {
+				onLine(line)
+			}
+		case <-deadline.C:
+			return "", fmt.Errorf("engine did not answer with %q in %s", prefix, timeout)
+		}
+	}
+}
+
+func (e *uciEngine) isReady(timeout time.Duration) error {
+	if err := e.send("isready"); err != nil {
+		return err
+	}
+
+	_, err := e.readUntil("readyok", timeout, nil)
+	return err
+}
+
+// setOption sets an option the engine supports, others are skipped so
+// engines without e.g. Skill Level can be used as well.
+func (e *uciEngine) setOption(name string, value interface{}) error {
+	if _, ok := e.options[name]; !ok {
+		return nil
+	}
+
+	return e.send(fmt.Sprintf("setoption name %s value %v", name, value))
+}
+
+// resetExtraOptions sets the options of the last search that extra doesn't
+// set back to their defaults.
+func (e *uciEngine) resetExtraOptions(extra map[string]string) error {
+	for name := range e.extra {
+		if _, ok := extra[name]; ok {
+			continue
+		}
+
+		if err := e.setOption(name, e.options[name].Default); err != nil {
+			return err
+		}
+	}
+
+	e.extra = nil
+	return nil
+}
+
+func (e *uciEngine) setExtraOptions(extra map[string]string) error {
+	for name, value := range extra {
+		if err := e.setOption(name, value); err != nil {
+			return err
+		}
+	}
+
+	e.extra = extra
+	return nil
+}
+
+func (e *uciEngine) newGame(gameID string) error {
+	if err := e.send("ucinewgame"); err != nil {
+		return err
+	}
+
+	if err := e.isReady(readyTimeout); err != nil {
+		return err
+	}
+
+	e.gameID = gameID
+	return nil
+}
+
+// Score is an evaluation from the point of view of the side to move.
+type Score struct {
+	Centipawns int
+	Mate       int // moves to mate, negative when getting mated, 0 if none
+}
+
+// Candidate is one of the moves the engine considered, with its score.
+type Candidate struct {
+	Move  string
+	Score Score
+}
+
+// SearchResult is the engine's choice together with its evaluation and the
+// best lines it found, best first.
+type SearchResult struct {
+	BestMove   string
+	Score      Score
+	Candidates []Candidate
+}
+
+// pvLine is a principal variation reported in an info line.
+type pvLine struct {
+	multiPV int
+	depth   int
+	Candidate
+}
+
+// parseInfo reads a principal variation out of an info line, ok is false for
+// lines without an exact score and a move.
+func parseInfo(line string) (info pvLine, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || fields[0] != "info" {
+		return pvLine{}, false
+	}
+
+	info.multiPV = 1
+	hasScore := false
+
+	for i := 1; i < len(fields); i++ {
+		switch fields[i] {
+		case "depth", "multipv":
+			if i+1 >= len(fields) {
+				return pvLine{}, false
+			}
+
+			value, err := strconv.Atoi(fields[i+1])
+			if err != nil {
+				return pvLine{}, false
+			}
+
+			if fields[i] == "depth" {
+				info.depth = value
+			} else {
+				info.multiPV = value
+			}
+			i++
+		case "score":
+			if i+2 >= len(fields) {
+				return pvLine{}, false
+			}
+
+			value, err := strconv.Atoi(fields[i+2])
+			if err != nil {
+				return pvLine{}, false
+			}
+
+			switch fields[i+1] {
+			case "cp":
+				info.Score = Score{Centipawns: value}
+			case "mate":
+				info.Score = Score{Mate: value}
+			default:
+				return pvLine{}, false
+			}
+			hasScore = true
+			i += 2
+		case "lowerbound", "upperbound":
+			// aspiration window results, the exact score follows
+			return pvLine{}, false
+		case "pv":
+			if i+1 >= len(fields) {
+				return pvLine{}, false
+			}
+
+			info.Move = fields[i+1]
+			return info, hasScore
+		}
+	}
+
+	return pvLine{}, false
+}
+
+// Position is what the engine searches: the moves played since FEN. Only the
+// moves since the last irreversible move are needed to detect repetitions,
+// so the command stays short however long the game gets.
+type Position struct {
+	FEN   string // empty for the starting position
+	Moves []string
+}
+
+func (p Position) command() string {
+	command := "position startpos"
+	if p.FEN != "" {
+		command = "position fen " + p.FEN
+	}
+
+	if len(p.Moves) > 0 {
+		command += " moves " + strings.Join(p.Moves, " ")
+	}
+	return command
+}
+
+func (e *uciEngine) search(position Position, depth int) (*SearchResult, error) {
+	if err := e.send(position.command()); err != nil {
+		return nil, err
+	}
+
+	if err := e.send(fmt.Sprintf("go depth %d", depth)); err != nil {
+		return nil, err
+	}
+
+	// lines of deeper iterations replace the earlier ones
+	lines := map[int]pvLine{}
+
+	line, err := e.readUntil("bestmove", searchTimeout, func(line string) {
+		if info, ok := parseInfo(line); ok {
+			lines[info.multiPV] = info
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) < 2 || fields[1] == "(none)" || fields[1] == "0000" {
+		return nil, ErrNoMove
+	}
+
+	return newSearchResult(fields[1], lines), nil
+}
+
+// newSearchResult orders the reported lines, dropping the ones left over from
+// a shallower iteration than the best line.
+func newSearchResult(bestMove string, lines map[int]pvLine) *SearchResult {
+	result := &SearchResult{BestMove: bestMove}
+
+	best, ok := lines[1]
+	if !ok {
+		result.Candidates = []Candidate{{Move: bestMove}}
+		return result
+	}
+
+	result.Score = best.Score
+
+	for i := 1; i <= len(lines); i++ {
+		if line, ok := lines[i]; ok && line.depth >= best.depth {
+			result.Candidates = append(result.Candidates, line.Candidate)
+		}
+	}
+
+	return result
+}
+
+func (e *uciEngine) close() {
+	e.send("quit")
+	e.stdin.Close()
+
+	if e.cmd.Process != nil {
+		e.cmd.Process.Kill()
+	}
+	e.cmd.Wait()
+}
@@ -0,0 +1,167 @@
+// Package enginetest provides a fake UCI engine for tests, so the engine pool
+// and AI games can be exercised without a Stockfish binary.
+//
+// The fake runs inside the test binary: TestMain calls RunIfFakeUCI first
+// thing, and the engine pool is pointed at Path, which starts the test binary
+// again as the engine.
+package enginetest
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/notnil/chess"
+)
+
+const (
+	fakeUCIEnv = "SFON_FAKE_UCI"
+	scriptEnv  = "SFON_FAKE_UCI_SCRIPT"
+)
+
+// RunIfFakeUCI turns the process into the fake engine when it was started by
+// the engine pool, it doesn't return in that case.
+func RunIfFakeUCI() {
+	if os.Getenv(fakeUCIEnv) == "" {
+		return
+	}
+
+	script := strings.Fields(os.Getenv(scriptEnv))
+	if err := (FakeUCI{Script: script}).Serve(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "fake uci:", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// Path returns the engine path that starts the fake engine, playing the
+// script given as UCI moves of the whole game from the starting position.
+// Engines started later inherit the script through the environment.
+func Path(script ...string) string {
+	os.Setenv(fakeUCIEnv, "1")
+	os.Setenv(scriptEnv, strings.Join(script, " "))
+	return os.Args[0]
+}
+
+// FakeUCI answers the UCI commands the engine pool sends. It plays the move
+// of the script for the current ply when it is legal and otherwise the first
+// legal move in UCI order, so games stay deterministic.
+type FakeUCI struct {
+	Script []string
+}
+
+func (f FakeUCI) Serve(in io.Reader, out io.Writer) error {
+	scanner := bufio.NewScanner(in)
+	position := chess.StartingPosition()
+	ply := 0
+
+	reply := func(lines ...string) error {
+		for _, line := range lines {
+			if _, err := fmt.Fprintln(out, line); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		var err error
+		switch fields[0] {
+		case "uci":
+			err = reply(
+				"id name fakeuci",
+				"option name Skill Level type spin default 20 min 0 max 20",
+				"option name UCI_LimitStrength type check default false",
+				"option name UCI_Elo type spin default 1320 min 1320 max 3190",
+				"option name MultiPV type spin default 1 min 1 max 500",
+				"uciok",
+			)
+		case "isready":
+			err = reply("readyok")
+		case "position":
+			position, ply, err = parsePosition(fields[1:])
+		case "go":
+			move := f.pick(position, ply)
+			if move == "" {
+				err = reply("info depth 0 score mate 0", "bestmove (none)")
+			} else {
+				err = reply("info depth 1 multipv 1 score cp 0 pv "+move, "bestmove "+move)
+			}
+		case "quit":
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func (f FakeUCI) pick(position *chess.Position, ply int) string {
+	var legal []string
+	for _, move := range position.ValidMoves() {
+		legal = append(legal, (chess.UCINotation{}).Encode(position, move))
+	}
+	sort.Strings(legal)
+
+	if ply < len(f.Script) {
+		for _, move := range legal {
+			if move == f.Script[ply] {
+				return move
+			}
+		}
+	}
+
+	if len(legal) == 0 {
+		return ""
+	}
+	return legal[0]
+}
+
+// parsePosition reads the arguments of a position command and returns the
+// position with the number of plies played since the starting position.
+func parsePosition(args []string) (*chess.Position, int, error) {
+	position := chess.StartingPosition()
+	ply := 0
+
+	if len(args) >= 7 && args[0] == "fen" {
+		fen, err := chess.FEN(strings.Join(args[1:7], " "))
+		if err != nil {
+			return nil, 0, err
+		}
+		position = chess.NewGame(fen).Position()
+
+		fullMove, _ := strconv.Atoi(args[6])
+		ply = (fullMove - 1) * 2
+		if args[2] == "b" {
+			ply++
+		}
+		args = args[7:]
+	} else if len(args) > 0 && args[0] == "startpos" {
+		args = args[1:]
+	}
+
+	if len(args) > 0 && args[0] == "moves" {
+		for _, uciMove := range args[1:] {
+			move, err := (chess.UCINotation{}).Decode(position, uciMove)
+			if err != nil {
+				return nil, 0, err
+			}
+			position = position.Update(move)
+			ply++
+		}
+	}
+
+	return position, ply, nil
+}
@@ -0,0 +1,62 @@
+package game
+
+import (
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/store"
+)
+
+// NewGameRecord takes a snapshot of a finished room for the game archive.
+func NewGameRecord(room *models.Room) *store.GameRecord {
+	record := &store.GameRecord{
+		RoomID:      room.ID,
+		Moves:       append([]string{}, room.Moves...),
+		MoveTimes:   make([]int64, 0, len(room.MoveTimes)),
+		IsAI:        room.IsAI,
+		Result:      room.Outcome.String(),
+		Method:      room.Board.Method().String(),
+		Reason:      room.Reason,
+		GameTime:    int(room.TimeControl.Initial().Seconds()),
+		TimeControl: room.TimeControl.String(),
+		StartedAt:   room.StartedAt,
+		EndedAt:     room.EndedAt,
+	}
+
+	for _, spent := range room.MoveTimes {
+		record.MoveTimes = append(record.MoveTimes, spent.Milliseconds())
+	}
+
+	for _, player := range []*models.Player{room.Player1, room.Player2} {
+		if player == nil {
+			continue
+		}
+
+		playerRecord := store.PlayerRecord{IsAI: player.IsAI}
+		if player.Guess != nil {
+			playerRecord.Guess = *player.Guess
+		}
+
+		if player.Color != nil && *player.Color == "black" {
+			record.Black = playerRecord
+		} else {
+			record.White = playerRecord
+		}
+	}
+
+	if aiPlayer := getAIPlayer(room); aiPlayer != nil {
+		if aiPlayer.Rank != nil {
+			record.AIElo = *aiPlayer.Rank
+		}
+		if aiPlayer.Engine != nil {
+			record.Engine = *aiPlayer.Engine
+		}
+		if aiPlayer.AI != nil {
+			strength := aiPlayer.AI.Strength()
+			record.AIPersona = aiPlayer.AI.Persona().Name
+			record.AIEngineElo = strength.EngineElo
+			record.AIStrength = string(strength.Mode)
+			record.AISkillLevel = strength.SkillLevel
+		}
+	}
+
+	return record
+}
@@ -0,0 +1,122 @@
+package game
+
+import (
+	"log"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/utils"
+)
+
+var (
+	ErrDrawOfferPending = &Error{Code: "draw_offer_pending", Message: "A draw offer is already waiting for an answer"}
+	ErrNoDrawOffer      = &Error{Code: "no_draw_offer", Message: "Opponent has not offered a draw"}
+	ErrAbortTooLate     = &Error{Code: "abort_too_late", Message: "Game can only be aborted before your first move"}
+	ErrDrawNotClaimable = &Error{Code: "draw_not_claimable", Message: "No draw can be claimed in this position"}
+)
+
+// CanResign checks that the game is still running.
+func CanResign(room *models.Room) *Error {
+	if room.State == models.RoomEnded {
+		return ErrGameEnded
+	}
+	return nil
+}
+
+// OfferDraw records the player's draw offer until the opponent answers it or
+// makes a move.
+func OfferDraw(room *models.Room, player *models.Player) *Error {
+	if room.State == models.RoomEnded {
+		return ErrGameEnded
+	}
+
+	if room.DrawOffer != nil {
+		return ErrDrawOfferPending
+	}
+
+	room.DrawOffer = player
+	room.DrawOfferID++
+	return nil
+}
+
+// AnswerDrawOffer clears the opponent's pending draw offer, the caller ends
+// the game if it was accepted.
+func AnswerDrawOffer(room *models.Room, player *models.Player) *Error {
+	if room.State == models.RoomEnded {
+		return ErrGameEnded
+	}
+
+	if room.DrawOffer == nil || room.DrawOffer == player {
+		return ErrNoDrawOffer
+	}
+
+	room.DrawOffer = nil
+	return nil
+}
+
+// CanAbort checks that the player hasn't made a move yet, white moves first
+// so black may still abort after a single move.
+func CanAbort(room *models.Room, player *models.Player) *Error {
+	if room.State == models.RoomEnded {
+		return ErrGameEnded
+	}
+
+	movesMade := (len(room.Moves) + 1) / 2
+	if player.Color != nil && *player.Color == "black" {
+		movesMade = len(room.Moves) / 2
+	}
+
+	if movesMade > 0 {
+		return ErrAbortTooLate
+	}
+	return nil
+}
+
+// ClaimableDraws lists the draws the player on turn may claim, by the names
+// of their chess.Method.
+func ClaimableDraws(room *models.Room) []string {
+	var draws []string
+	for _, method := range room.Board.EligibleDraws() {
+		if method != chess.DrawOffer {
+			draws = append(draws, method.String())
+		}
+	}
+	return draws
+}
+
+// ClaimDraw checks the claim against the board and returns the draw method
+// to end the game with, an empty method claims any draw that applies.
+func ClaimDraw(room *models.Room, player *models.Player, method string) (chess.Method, *Error) {
+	if room.State == models.RoomEnded {
+		return chess.NoMethod, ErrGameEnded
+	}
+
+	if room.Turn != player {
+		return chess.NoMethod, ErrNotYourTurn
+	}
+
+	for _, eligible := range room.Board.EligibleDraws() {
+		if eligible == chess.DrawOffer {
+			continue
+		}
+
+		if method == "" || method == eligible.String() {
+			return eligible, nil
+		}
+	}
+
+	return chess.NoMethod, ErrDrawNotClaimable
+}
+
+func RejectCommand(player *models.Player, room *models.Room, command protocol.InboundType, commandErr *Error) {
+	err := utils.SafelyNotifyPlayer(player, protocol.NewEnvelope(protocol.StateCommandRejected, room.ID, "Command rejected", &protocol.CommandRejectedData{
+		Command: string(command),
+		Code:    commandErr.Code,
+		Reason:  commandErr.Message,
+	}))
+
+	if err != nil {
+		log.Println("Error sending command rejection:", err)
+	}
+}
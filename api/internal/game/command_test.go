@@ -0,0 +1,101 @@
+package game
+
+import (
+	"testing"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/clock"
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/timer"
+)
+
+// newTestRoom sets up a running game from fen between two players without
+// connections, white is returned first.
+func newTestRoom(t *testing.T, fen string) (*models.Room, *models.Player, *models.Player) {
+	t.Helper()
+
+	options := []func(*chess.Game){chess.UseNotation(chess.UCINotation{})}
+	if fen != "" {
+		position, err := chess.FEN(fen)
+		if err != nil {
+			t.Fatal(err)
+		}
+		options = append(options, position)
+	}
+
+	fake := clock.NewFake(time.Unix(0, 0))
+	board := chess.NewGame(options...)
+	room := &models.Room{ID: "test", Board: board, FEN: board.FEN(), RootFEN: board.FEN(), Clock: fake, State: models.RoomPlaying}
+
+	players := make([]*models.Player, 2)
+	for i, color := range []string{"white", "black"} {
+		color := color
+		players[i] = &models.Player{Room: room, Color: &color, Timer: timer.NewTimer(fake, timer.SuddenDeath(time.Hour), 0, nil, func() {})}
+	}
+
+	room.Player1, room.Player2 = players[0], players[1]
+	room.Turn = room.Player1
+	if board.Position().Turn() == chess.Black {
+		room.Turn = room.Player2
+	}
+
+	return room, players[0], players[1]
+}
+
+// play makes the moves in turn, failing the test on a rejected move.
+func play(t *testing.T, room *models.Room, moves ...string) {
+	t.Helper()
+
+	for _, move := range moves {
+		if err := ApplyMove(room, room.Turn, move); err != nil {
+			t.Fatalf("move %s: %v", move, err)
+		}
+		ChangeTurn(room)
+	}
+}
+
+func TestClaimDraw(t *testing.T) {
+	shuffle := []string{"g1f3", "g8f6", "f3g1", "f6g8"}
+	fiftyMoves := "8/8/8/4k3/8/8/8/4K2R w - - 99 80"
+
+	tests := []struct {
+		name       string
+		fen        string
+		moves      []string
+		claimWhite bool
+		method     string
+		want       chess.Method
+		wantErr    *Error
+	}{
+		{"threefold repetition", "", append(shuffle, shuffle...), true, "", chess.ThreefoldRepetition, nil},
+		{"threefold repetition by name", "", append(shuffle, shuffle...), true, "ThreefoldRepetition", chess.ThreefoldRepetition, nil},
+		{"position seen twice", "", shuffle, true, "", chess.NoMethod, ErrDrawNotClaimable},
+		{"repetition claimed off turn", "", append(shuffle, shuffle...), false, "", chess.NoMethod, ErrNotYourTurn},
+		{"fifty moves", fiftyMoves, []string{"h1h2"}, false, "", chess.FiftyMoveRule, nil},
+		{"fifty moves by name", fiftyMoves, []string{"h1h2"}, false, "FiftyMoveRule", chess.FiftyMoveRule, nil},
+		{"fifty moves not reached", fiftyMoves, nil, true, "FiftyMoveRule", chess.NoMethod, ErrDrawNotClaimable},
+		{"pawn move resets fifty moves", "8/8/8/4k3/8/8/4P3/4K3 w - - 99 80", []string{"e2e3"}, false, "", chess.NoMethod, ErrDrawNotClaimable},
+		{"other draw than the one claimed", "", append(shuffle, shuffle...), true, "FiftyMoveRule", chess.NoMethod, ErrDrawNotClaimable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			room, white, black := newTestRoom(t, tt.fen)
+			play(t, room, tt.moves...)
+
+			claimant := black
+			if tt.claimWhite {
+				claimant = white
+			}
+
+			method, err := ClaimDraw(room, claimant, tt.method)
+			if err != tt.wantErr {
+				t.Fatalf("claim answered %v, want %v", err, tt.wantErr)
+			}
+			if method != tt.want {
+				t.Errorf("claimed %s, want %s", method, tt.want)
+			}
+		})
+	}
+}
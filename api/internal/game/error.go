@@ -0,0 +1,12 @@
+package game
+
+// Error is why a move, guess or command was rejected, Code is what clients
+// match on.
+type Error struct {
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
@@ -0,0 +1,97 @@
+package game
+
+import (
+	"log"
+
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/utils"
+)
+
+const (
+	GuessAI    = "AI"
+	GuessHuman = "Human"
+)
+
+var (
+	ErrInvalidGuess    = &Error{Code: "invalid_guess", Message: "Guess must be either AI or Human"}
+	ErrGameInProgress  = &Error{Code: "game_in_progress", Message: "Guess can only be submitted after the game ended"}
+	ErrAlreadyGuessed  = &Error{Code: "already_guessed", Message: "Guess has already been submitted"}
+	ErrGuessNotAllowed = &Error{Code: "guess_not_allowed", Message: "Player is not allowed to guess"}
+)
+
+// HandleGuess records the player's guess about their opponent and only then
+// reveals who the opponent actually was.
+func HandleGuess(player *models.Player, room *models.Room, guess string) *Error {
+	if guess != GuessAI && guess != GuessHuman {
+		return ErrInvalidGuess
+	}
+
+	if room.State != models.RoomEnded {
+		return ErrGameInProgress
+	}
+
+	if player.IsAI {
+		return ErrGuessNotAllowed
+	}
+
+	if player.Guess != nil {
+		return ErrAlreadyGuessed
+	}
+
+	player.Guess = &guess
+
+	verdict := &protocol.VerdictData{
+		Guess:   guess,
+		Correct: (guess == GuessAI) == room.IsAI,
+		IsAI:    room.IsAI,
+	}
+
+	if aiPlayer := getAIPlayer(room); aiPlayer != nil {
+		verdict.AIMeta = &protocol.AIMeta{}
+		if aiPlayer.Rank != nil {
+			verdict.AIMeta.Rank = *aiPlayer.Rank
+		}
+		if aiPlayer.Engine != nil {
+			verdict.AIMeta.Engine = *aiPlayer.Engine
+		}
+		if aiPlayer.AI != nil {
+			strength := aiPlayer.AI.Strength()
+			verdict.AIMeta.Persona = aiPlayer.AI.Persona().Name
+			verdict.AIMeta.EngineElo = strength.EngineElo
+			verdict.AIMeta.Strength = string(strength.Mode)
+		}
+	}
+
+	err := utils.SafelyNotifyPlayer(player, protocol.NewEnvelope(protocol.StateVerdict, room.ID, "Verdict", verdict))
+
+	if err != nil {
+		log.Println("Error sending verdict:", err)
+	}
+
+	closeConnection(player)
+
+	return nil
+}
+
+// GuessesDone reports whether every human player in the room has guessed.
+func GuessesDone(room *models.Room) bool {
+	for _, player := range []*models.Player{room.Player1, room.Player2} {
+		if player != nil && !player.IsAI && player.Guess == nil {
+			return false
+		}
+	}
+	return true
+}
+
+func RejectGuess(player *models.Player, room *models.Room, guess string, guessErr *Error) {
+	err := utils.SafelyNotifyPlayer(player, protocol.NewEnvelope(protocol.StateGuessRejected, room.ID, "Guess rejected", &protocol.GuessRejectedData{
+		Guess:  guess,
+		Code:   guessErr.Code,
+		Reason: guessErr.Message,
+	}))
+
+	if err != nil {
+		log.Println("Error sending guess rejection:", err)
+	}
+}
@@ -1,63 +1,94 @@
 package game
 
 import (
+	"log"
+
+	"github.com/notnil/chess"
 	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
 	"github.com/style77/stockfish-or-not/internal/utils"
 )
 
-func HandleGameEnd(playerTurn *models.Player, room *models.Room, reason string, result *utils.GameResult) {
-	room.Mux.Lock()
-	defer room.Mux.Unlock()
+// HandleGameEnd finishes the game and reports whether this call ended it,
+// a game that has already ended is left untouched.
+func HandleGameEnd(playerTurn *models.Player, room *models.Room, reason string, result *utils.GameResult) bool {
+	if room.State == models.RoomEnded {
+		return false
+	}
 
-	room.GameEnded = true
+	room.State = models.RoomEnded
+	room.Outcome = result.Outcome
+	room.Reason = reason
+	room.EndedAt = room.Clock.Now()
+	room.DrawOffer = nil
 
-	var aiPlayer *models.Player
-	if room.IsAI {
-		if room.Player1.IsAI {
-			aiPlayer = room.Player1
-		} else {
-			aiPlayer = room.Player2
-		}
+	recordMethod(room, result)
 
-		if aiPlayer.AI != nil {
-			aiPlayer.AI.Close()
-		}
+	// the opponent is only revealed in the verdict, after the player guessed
+	utils.NotifyBothPlayers(room, protocol.NewEnvelope(protocol.StateGameEnded, room.ID, "Game ended", &protocol.GameEndedData{
+		Result: result.Outcome.String(),
+		Reason: reason,
+	}))
+
+	if room.Player1 != nil && room.Player1.Timer != nil {
+		room.Player1.Timer.StopTimer()
+	}
+
+	if room.Player2 != nil && room.Player2.Timer != nil {
+		room.Player2.Timer.StopTimer()
+	}
+
+	room.Turn = nil
+
+	return true
+}
+
+// recordMethod ends the game on the board too, so the archive and PGN know
+// how it ended. Boards that ended the game themselves are left alone.
+func recordMethod(room *models.Room, result *utils.GameResult) {
+	if room.Board.Outcome() != chess.NoOutcome {
+		return
 	}
 
-	utils.NotifyBothPlayers(room, map[string]interface{}{
-		"state":   99,
-		"roomID":  room.ID,
-		"message": "Game ended",
-		"data": map[string]interface{}{
-			"result": result.Outcome.String(),
-			"reason": reason,
-			"isAI":   room.IsAI,
-			"AIMeta": map[string]interface{}{
-				"rank":   aiPlayer.Rank,
-				"engine": aiPlayer.Engine,
-			},
-		},
-	})
-
-	if room.Player1 != nil {
-		if room.Player1.Timer != nil {
-			room.Player1.Timer.StopTimer()
+	switch result.Method {
+	case chess.NoMethod:
+	case chess.Resignation:
+		if result.Outcome == chess.WhiteWon {
+			room.Board.Resign(chess.Black)
+		} else {
+			room.Board.Resign(chess.White)
 		}
-		if room.Player1.Conn != nil {
-			room.Player1.Conn.Close()
+	default:
+		if err := room.Board.Draw(result.Method); err != nil {
+			log.Println("Error recording", result.Method, "in room", room.ID+":", err)
 		}
 	}
+}
 
-	if room.Player2 != nil {
-		if room.Player2.Timer != nil {
-			room.Player2.Timer.StopTimer()
-		}
-		if room.Player2.Conn != nil {
-			room.Player2.Conn.Close()
+func getAIPlayer(room *models.Room) *models.Player {
+	if !room.IsAI {
+		return nil
+	}
+
+	if room.Player1 != nil && room.Player1.IsAI {
+		return room.Player1
+	}
+	return room.Player2
+}
+
+func CloseConnections(room *models.Room) {
+	for _, player := range []*models.Player{room.Player1, room.Player2} {
+		if player != nil {
+			closeConnection(player)
 		}
 	}
+}
 
-	room.Player1 = nil
-	room.Player2 = nil
-	room.Turn = nil
+func closeConnection(player *models.Player) {
+	player.Mux.Lock()
+	defer player.Mux.Unlock()
+
+	if player.Conn != nil {
+		player.Conn.Close()
+	}
 }
@@ -0,0 +1,151 @@
+package game
+
+import (
+	"log"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/engine"
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/utils"
+)
+
+var (
+	ErrMalformedMove = &Error{Code: "malformed_move", Message: "Move is not in UCI notation"}
+	ErrIllegalMove   = &Error{Code: "illegal_move", Message: "Move is not legal in the current position"}
+	ErrNotYourTurn   = &Error{Code: "not_your_turn", Message: "It is not your turn"}
+	ErrGameEnded     = &Error{Code: "game_ended", Message: "Game has already ended"}
+	ErrTimeUp        = &Error{Code: "time_up", Message: "Time ran out before the move was made"}
+)
+
+// ApplyMove validates move against the room's board and records it. The
+// board is only updated when the move is legal for the player on turn and
+// was made in time, ErrTimeUp means the caller ends the game on time.
+func ApplyMove(room *models.Room, player *models.Player, move string) *Error {
+	if room.State == models.RoomEnded {
+		return ErrGameEnded
+	}
+
+	if room.Turn != player {
+		return ErrNotYourTurn
+	}
+
+	if _, err := (chess.UCINotation{}).Decode(nil, move); err != nil {
+		return ErrMalformedMove
+	}
+
+	decoded, err := (chess.UCINotation{}).Decode(room.Board.Position(), move)
+	if err != nil || !isValidMove(room.Board.Position(), decoded) {
+		return ErrIllegalMove
+	}
+
+	// the clock stops as soon as the move is accepted, it started on the
+	// player's first turn
+	spent, flagged := player.Timer.CompleteMove()
+	if flagged {
+		return ErrTimeUp
+	}
+
+	if err := room.Board.Move(decoded); err != nil {
+		return ErrIllegalMove
+	}
+
+	room.Moves = append(room.Moves, move)
+	room.FEN = room.Board.FEN()
+
+	if room.Board.Position().HalfMoveClock() == 0 {
+		room.RootFEN = room.FEN
+		room.RootMoves = room.RootMoves[:0]
+	} else {
+		room.RootMoves = append(room.RootMoves, move)
+	}
+
+	// moving instead of answering declines the opponent's draw offer
+	if room.DrawOffer != nil && room.DrawOffer != player {
+		room.DrawOffer = nil
+	}
+
+	room.MoveTimes = append(room.MoveTimes, spent)
+	return nil
+}
+
+func isValidMove(position *chess.Position, move *chess.Move) bool {
+	for _, valid := range position.ValidMoves() {
+		if valid.S1() == move.S1() && valid.S2() == move.S2() && valid.Promo() == move.Promo() {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckEndGameStates reports whether the last move ended the game.
+func CheckEndGameStates(room *models.Room) (*utils.GameResult, bool) {
+	return utils.CheckEndGameStates(room.Board)
+}
+
+// EnginePosition is the current position for the engine to search.
+func EnginePosition(room *models.Room) engine.Position {
+	return engine.Position{
+		FEN:   room.RootFEN,
+		Moves: append([]string(nil), room.RootMoves...),
+	}
+}
+
+// MoveChoice gathers what the AI's move selector picks from.
+func MoveChoice(room *models.Room, player *models.Player, result *engine.SearchResult) engine.Choice {
+	choice := engine.Choice{
+		Position:   room.Board.Position(),
+		Candidates: result.Candidates,
+		BestMove:   result.BestMove,
+	}
+
+	if player.Timer != nil {
+		choice.Remaining = player.Timer.Remaining()
+	}
+
+	return choice
+}
+
+// MoveContext describes the position the player is about to play move in,
+// for the AI's think time.
+func MoveContext(room *models.Room, player *models.Player, move string) engine.MoveContext {
+	position := room.Board.Position()
+
+	ctx := engine.MoveContext{
+		Increment:  room.TimeControl.Increment,
+		MoveNumber: len(room.Moves)/2 + 1,
+		LegalMoves: len(position.ValidMoves()),
+	}
+
+	if player.Timer != nil {
+		ctx.Remaining = player.Timer.Remaining()
+	}
+
+	for _, piece := range position.Board().SquareMap() {
+		if piece.Type() != chess.King && piece.Type() != chess.Pawn {
+			ctx.Pieces++
+		}
+	}
+
+	played := room.Board.Moves()
+	if len(played) > 0 {
+		last := played[len(played)-1]
+		next, err := (chess.UCINotation{}).Decode(position, move)
+
+		ctx.Recapture = err == nil && last.HasTag(chess.Capture) && next.HasTag(chess.Capture) && next.S2() == last.S2()
+	}
+
+	return ctx
+}
+
+func RejectMove(player *models.Player, room *models.Room, move string, moveErr *Error) {
+	err := utils.SafelyNotifyPlayer(player, protocol.NewEnvelope(protocol.StateMoveRejected, room.ID, "Move rejected", &protocol.MoveRejectedData{
+		Move:   move,
+		Code:   moveErr.Code,
+		Reason: moveErr.Message,
+	}))
+
+	if err != nil {
+		log.Println("Error sending move rejection:", err)
+	}
+}
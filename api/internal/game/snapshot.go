@@ -0,0 +1,73 @@
+package game
+
+import (
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/timer"
+)
+
+func DescribeTimeControl(tc timer.TimeControl) *protocol.TimeControlData {
+	periods := make([]protocol.PeriodData, 0, len(tc.Periods))
+	for _, period := range tc.Periods {
+		periods = append(periods, protocol.PeriodData{Moves: period.Moves, Time: int(period.Time.Seconds())})
+	}
+
+	return &protocol.TimeControlData{
+		Name:      tc.Name,
+		Periods:   periods,
+		Increment: int(tc.Increment.Seconds()),
+		Delay:     int(tc.Delay.Seconds()),
+		DelayMode: string(tc.DelayMode),
+	}
+}
+
+// Snapshot describes the current state of the game from the player's point
+// of view.
+func Snapshot(room *models.Room, player *models.Player) *protocol.SnapshotData {
+	snapshot := &protocol.SnapshotData{
+		GameTime:    int(room.TimeControl.Initial().Seconds()),
+		TimeControl: DescribeTimeControl(room.TimeControl),
+		FEN:         room.FEN,
+		Moves:       append([]string(nil), room.Moves...),
+		Turn:        "white",
+	}
+
+	if room.Board.Position().Turn() == chess.Black {
+		snapshot.Turn = "black"
+	}
+
+	if player.Color != nil {
+		snapshot.Color = *player.Color
+	}
+
+	snapshot.Clock = Clock(room)
+	snapshot.WhiteTime = int(snapshot.Clock.White / 1000)
+	snapshot.BlackTime = int(snapshot.Clock.Black / 1000)
+
+	return snapshot
+}
+
+// Clock reads both players' clocks so clients can render them locally.
+func Clock(room *models.Room) *protocol.ClockData {
+	clock := &protocol.ClockData{ServerTime: room.Clock.Now().UnixMilli()}
+
+	for _, p := range []*models.Player{room.Player1, room.Player2} {
+		if p == nil || p.Timer == nil || p.Color == nil {
+			continue
+		}
+
+		remaining := p.Timer.Remaining().Milliseconds()
+		if *p.Color == "white" {
+			clock.White = remaining
+		} else {
+			clock.Black = remaining
+		}
+
+		if p.Timer.Running() {
+			clock.Running = *p.Color
+		}
+	}
+
+	return clock
+}
@@ -0,0 +1,28 @@
+package game
+
+import (
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/utils"
+)
+
+// TimeoutResult decides a game lost on time by the player with color. The
+// opponent only wins if they could still deliver mate, otherwise it is a draw.
+func TimeoutResult(room *models.Room, color string) *utils.GameResult {
+	winner, winnerColor := chess.White, "white"
+	if color == "white" {
+		winner, winnerColor = chess.Black, "black"
+	}
+
+	if !utils.HasMatingMaterial(room.Board.Position().Board(), winner) {
+		return &utils.GameResult{
+			Outcome:       chess.Draw,
+			OutcomeReason: "timeout vs insufficient material",
+		}
+	}
+
+	return &utils.GameResult{
+		Outcome:       utils.WinFor(winnerColor),
+		OutcomeReason: "Time is up",
+	}
+}
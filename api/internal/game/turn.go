@@ -4,43 +4,33 @@ import (
 	"log"
 
 	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
 	"github.com/style77/stockfish-or-not/internal/utils"
 )
 
 func ChangeTurn(room *models.Room) {
 	var nextTurnPlayer *models.Player
-	var lastTurnPlayer *models.Player
 
 	if room.Turn == room.Player1 {
 		nextTurnPlayer = room.Player2
-		lastTurnPlayer = room.Player1
 	} else {
 		nextTurnPlayer = room.Player1
-		lastTurnPlayer = room.Player2
 	}
 
-	room.Mux.Lock()
 	room.Turn = nextTurnPlayer
-	room.Mux.Unlock()
+	claimableDraws := ClaimableDraws(room)
 
-	err := utils.SafelyNotifyPlayer(nextTurnPlayer, map[string]interface{}{
-		"message": "Your turn",
-		"roomID":  room.ID,
-		"state":   79,
-	})
+	// the clock is started on the player's first turn, starting it again
+	// is a no-op and so is resuming it right after
+	nextTurnPlayer.Timer.StartTimer()
+	nextTurnPlayer.Timer.ResumeTimer()
+
+	err := utils.SafelyNotifyPlayer(nextTurnPlayer, protocol.NewEnvelope(protocol.StateYourTurn, room.ID, "Your turn", &protocol.YourTurnData{
+		Clock:          Clock(room),
+		ClaimableDraws: claimableDraws,
+	}))
 
 	if err != nil {
 		log.Println("Error sending turn message to Player 2:", err)
 	}
-
-	// if both of timers are stopped then start the next turn player's timer
-	if !lastTurnPlayer.Timer.IsStarted && !nextTurnPlayer.Timer.IsStarted {
-		nextTurnPlayer.Timer.StartTimer()
-		return
-	} else if !nextTurnPlayer.Timer.IsStarted {
-		nextTurnPlayer.Timer.StartTimer()
-	}
-
-	lastTurnPlayer.Timer.PauseTimer()
-	nextTurnPlayer.Timer.ResumeTimer()
 }
@@ -0,0 +1,40 @@
+package games
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/style77/stockfish-or-not/internal"
+	"github.com/style77/stockfish-or-not/internal/pgn"
+	"github.com/style77/stockfish-or-not/internal/store"
+)
+
+// HandlePGN exports an archived game. Games are only archived once their
+// guess phase is over, so the PGN can't tell a player who they played before
+// they guessed.
+func HandlePGN(w http.ResponseWriter, r *http.Request, app *internal.App) {
+	roomID := r.PathValue("roomID")
+
+	record, err := app.Store.Get(roomID)
+	if errors.Is(err, store.ErrGameNotFound) {
+		http.Error(w, "Game not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error loading game", roomID+":", err)
+		http.Error(w, "Error loading game", http.StatusInternalServerError)
+		return
+	}
+
+	encoded, err := pgn.Encode(record)
+	if err != nil {
+		log.Println("Error encoding PGN for game", roomID+":", err)
+		http.Error(w, "Error encoding game", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-chess-pgn")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+roomID+`.pgn"`)
+	w.Write([]byte(encoded + "\n"))
+}
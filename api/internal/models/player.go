@@ -1,7 +1,10 @@
 package models
 
 import (
+	"sync"
+
 	"github.com/gorilla/websocket"
+	"github.com/style77/stockfish-or-not/internal/clock"
 	"github.com/style77/stockfish-or-not/internal/engine"
 	"github.com/style77/stockfish-or-not/internal/timer"
 )
@@ -16,4 +19,12 @@ type Player struct {
 
 	Timer *timer.Timer
 	Color *string
+	Guess *string // guess about the opponent, submitted after the game ended
+
+	// SessionToken lets a human player reattach to the game after the
+	// connection dropped, Conn is replaced on reconnect.
+	SessionToken string
+	Disconnected bool
+	ForfeitTimer clock.Timer
+	Mux          sync.Mutex // guards Room, Conn, Disconnected and ForfeitTimer
 }
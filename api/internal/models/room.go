@@ -1,17 +1,58 @@
 package models
 
 import (
-	"sync"
+	"time"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/clock"
+	"github.com/style77/stockfish-or-not/internal/timer"
+)
+
+// RoomState is where the room is in its lifecycle, only the room's event loop
+// moves it on.
+type RoomState int
+
+const (
+	RoomMatching RoomState = iota // players are being set up
+	RoomPlaying
+	RoomEnded  // the game is over, guesses are still accepted
+	RoomClosed // the guesses are in or their window is over
 )
 
+// Room is a single game. Once its event loop started, the loop is the only
+// goroutine that touches the game, everyone else hands their work to it
+// through Events.
 type Room struct {
 	ID      string
 	Player1 *Player
 	Player2 *Player
 	IsAI    bool
 	Moves   []string
-	Mux     sync.Mutex
-	Turn    *Player
+	// MoveTimes holds the clock time spent on each move in Moves
+	MoveTimes []time.Duration
+	Board     *chess.Game // authoritative board, moves are validated against it
+	FEN       string      // current position of Board
+	// RootFEN is the position after the last irreversible move (capture or
+	// pawn move), RootMoves the moves played since. That's all the history
+	// the engine needs.
+	RootFEN   string
+	RootMoves []string
+	Turn      *Player
+	// DrawOffer is the player whose draw offer is waiting for an answer,
+	// DrawOfferID counts the offers so a late answer can't hit a newer one
+	DrawOffer   *Player
+	DrawOfferID int
+
+	TimeControl timer.TimeControl
+	Clock       clock.Clock // the clocks and timestamps of the game run on it
+
+	State   RoomState
+	Outcome chess.Outcome
+	Reason  string
+
+	Events chan interface{}
+	Done   chan struct{} // closed once the event loop exited
 
-	GameEnded bool
+	StartedAt time.Time
+	EndedAt   time.Time
 }
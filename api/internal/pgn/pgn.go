@@ -0,0 +1,83 @@
+package pgn
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/notnil/chess"
+	"github.com/style77/stockfish-or-not/internal/store"
+)
+
+const (
+	event = "Stockfish or not"
+	site  = "stockfish-or-not"
+)
+
+// Encode renders an archived game as PGN with the Seven Tag Roster followed
+// by the game's own tags.
+func Encode(record *store.GameRecord) (string, error) {
+	game := chess.NewGame(chess.TagPairs([]*chess.TagPair{
+		{Key: "Event", Value: event},
+		{Key: "Site", Value: site},
+		{Key: "Date", Value: record.StartedAt.UTC().Format("2006.01.02")},
+		{Key: "Round", Value: "-"},
+		{Key: "White", Value: playerName(record, record.White)},
+		{Key: "Black", Value: playerName(record, record.Black)},
+		{Key: "Result", Value: record.Result},
+		{Key: "IsAI", Value: strconv.FormatBool(record.IsAI)},
+		{Key: "TimeControl", Value: timeControl(record)},
+		{Key: "Termination", Value: record.Reason},
+	}))
+
+	if record.IsAI {
+		game.AddTagPair("AIElo", strconv.Itoa(record.AIElo))
+		game.AddTagPair("Engine", record.Engine)
+		if record.AIPersona != "" {
+			game.AddTagPair("Persona", record.AIPersona)
+		}
+	}
+
+	for _, uciMove := range record.Moves {
+		move, err := (chess.UCINotation{}).Decode(game.Position(), uciMove)
+		if err != nil {
+			return "", fmt.Errorf("decoding move %s: %w", uciMove, err)
+		}
+
+		if err := game.Move(move); err != nil {
+			return "", fmt.Errorf("applying move %s: %w", uciMove, err)
+		}
+	}
+
+	// games lost on time leave the board without an outcome, so the result
+	// is set explicitly to get the right game termination marker
+	if game.Outcome() == chess.NoOutcome {
+		switch chess.Outcome(record.Result) {
+		case chess.WhiteWon:
+			game.Resign(chess.Black)
+		case chess.BlackWon:
+			game.Resign(chess.White)
+		case chess.Draw:
+			game.Draw(chess.DrawOffer)
+		}
+	}
+
+	return game.String(), nil
+}
+
+func timeControl(record *store.GameRecord) string {
+	if record.TimeControl != "" {
+		return record.TimeControl
+	}
+	return strconv.Itoa(record.GameTime)
+}
+
+func playerName(record *store.GameRecord, player store.PlayerRecord) string {
+	if !player.IsAI {
+		return "Human"
+	}
+
+	if record.Engine != "" {
+		return record.Engine
+	}
+	return "AI"
+}
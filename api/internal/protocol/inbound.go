@@ -0,0 +1,118 @@
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+type Inbound struct {
+	Version int             `json:"v"`
+	Type    InboundType     `json:"type"`
+	Data    json.RawMessage `json:"data"`
+}
+
+type MoveMessage struct {
+	Move        string `json:"move"` // UCI notation
+	IsFirstMove bool   `json:"isFirstMove,omitempty"`
+}
+
+type GuessMessage struct {
+	Guess string `json:"guess"` // "AI" or "Human"
+}
+
+// CommandMessage is the data of the game commands that need nothing but
+// their type (resign, draw offers and abort). Clients send an empty object.
+type CommandMessage struct{}
+
+type ClaimDrawMessage struct {
+	// Method is ThreefoldRepetition or FiftyMoveRule, empty claims whichever
+	// applies
+	Method string `json:"method,omitempty"`
+}
+
+// InboundMessages lists the data expected for every client message type.
+var InboundMessages = []struct {
+	Type InboundType
+	Data interface{}
+}{
+	{TypeMove, MoveMessage{}},
+	{TypeGuess, GuessMessage{}},
+	{TypeResign, CommandMessage{}},
+	{TypeOfferDraw, CommandMessage{}},
+	{TypeAcceptDraw, CommandMessage{}},
+	{TypeDeclineDraw, CommandMessage{}},
+	{TypeAbort, CommandMessage{}},
+	{TypeClaimDraw, ClaimDrawMessage{}},
+}
+
+type DecodeError struct {
+	Code   string
+	Reason string
+}
+
+func (e *DecodeError) Error() string {
+	return e.Reason
+}
+
+func decodeError(code, format string, args ...interface{}) *DecodeError {
+	return &DecodeError{Code: code, Reason: fmt.Sprintf(format, args...)}
+}
+
+func decodeStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// Decode parses a client message and returns its type together with a
+// pointer to the matching message struct.
+func Decode(data []byte) (InboundType, interface{}, *DecodeError) {
+	var inbound Inbound
+	if err := decodeStrict(data, &inbound); err != nil {
+		return "", nil, decodeError("malformed_message", "Message is not a valid envelope: %v", err)
+	}
+
+	if inbound.Version != Version {
+		return "", nil, decodeError("unsupported_version", "Protocol version %d is not supported, expected %d", inbound.Version, Version)
+	}
+
+	if len(inbound.Data) == 0 {
+		return "", nil, decodeError("missing_data", "Message of type %q has no data", inbound.Type)
+	}
+
+	switch inbound.Type {
+	case TypeMove:
+		var msg MoveMessage
+		if err := decodeStrict(inbound.Data, &msg); err != nil {
+			return "", nil, decodeError("malformed_message", "Invalid move message: %v", err)
+		}
+		if msg.Move == "" {
+			return "", nil, decodeError("missing_field", "Move message requires a move")
+		}
+		return inbound.Type, &msg, nil
+	case TypeGuess:
+		var msg GuessMessage
+		if err := decodeStrict(inbound.Data, &msg); err != nil {
+			return "", nil, decodeError("malformed_message", "Invalid guess message: %v", err)
+		}
+		if msg.Guess == "" {
+			return "", nil, decodeError("missing_field", "Guess message requires a guess")
+		}
+		return inbound.Type, &msg, nil
+	case TypeResign, TypeOfferDraw, TypeAcceptDraw, TypeDeclineDraw, TypeAbort:
+		var msg CommandMessage
+		if err := decodeStrict(inbound.Data, &msg); err != nil {
+			return "", nil, decodeError("malformed_message", "Invalid %s message: %v", inbound.Type, err)
+		}
+		return inbound.Type, &msg, nil
+	case TypeClaimDraw:
+		var msg ClaimDrawMessage
+		if err := decodeStrict(inbound.Data, &msg); err != nil {
+			return "", nil, decodeError("malformed_message", "Invalid claim draw message: %v", err)
+		}
+		return inbound.Type, &msg, nil
+	}
+
+	return "", nil, decodeError("unknown_type", "Unknown message type %q", inbound.Type)
+}
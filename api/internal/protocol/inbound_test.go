@@ -0,0 +1,60 @@
+package protocol
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		wantType InboundType
+		wantMsg  interface{}
+		wantCode string
+	}{
+		{"move", `{"v": 1, "type": "move", "data": {"move": "e2e4"}}`, TypeMove, &MoveMessage{Move: "e2e4"}, ""},
+		{"guess", `{"v": 1, "type": "guess", "data": {"guess": "AI"}}`, TypeGuess, &GuessMessage{Guess: "AI"}, ""},
+		{"command", `{"v": 1, "type": "resign", "data": {}}`, TypeResign, &CommandMessage{}, ""},
+		{"claim any draw", `{"v": 1, "type": "claimDraw", "data": {}}`, TypeClaimDraw, &ClaimDrawMessage{}, ""},
+		{"claim a draw", `{"v": 1, "type": "claimDraw", "data": {"method": "FiftyMoveRule"}}`, TypeClaimDraw, &ClaimDrawMessage{Method: "FiftyMoveRule"}, ""},
+
+		{"not json", `move e2e4`, "", nil, "malformed_message"},
+		{"unknown envelope field", `{"v": 1, "type": "move", "data": {"move": "e2e4"}, "roomID": "x"}`, "", nil, "malformed_message"},
+		{"unknown data field", `{"v": 1, "type": "move", "data": {"move": "e2e4", "promotion": "q"}}`, "", nil, "malformed_message"},
+		{"unknown command field", `{"v": 1, "type": "abort", "data": {"now": true}}`, "", nil, "malformed_message"},
+		{"missing version", `{"type": "move", "data": {"move": "e2e4"}}`, "", nil, "unsupported_version"},
+		{"newer version", `{"v": 2, "type": "move", "data": {"move": "e2e4"}}`, "", nil, "unsupported_version"},
+		{"missing data", `{"v": 1, "type": "resign"}`, "", nil, "missing_data"},
+		{"missing move", `{"v": 1, "type": "move", "data": {}}`, "", nil, "missing_field"},
+		{"missing guess", `{"v": 1, "type": "guess", "data": {"guess": ""}}`, "", nil, "missing_field"},
+		{"wrong field type", `{"v": 1, "type": "move", "data": {"move": 42}}`, "", nil, "malformed_message"},
+		{"unknown type", `{"v": 1, "type": "castle", "data": {}}`, "", nil, "unknown_type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgType, msg, err := Decode([]byte(tt.data))
+
+			if tt.wantCode != "" {
+				if err == nil {
+					t.Fatalf("decoded %s %+v, want error %s", msgType, msg, tt.wantCode)
+				}
+				if err.Code != tt.wantCode {
+					t.Errorf("error code %s (%s), want %s", err.Code, err.Reason, tt.wantCode)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("decoding: %s (%s)", err.Code, err.Reason)
+			}
+			if msgType != tt.wantType {
+				t.Errorf("type %s, want %s", msgType, tt.wantType)
+			}
+			if !reflect.DeepEqual(msg, tt.wantMsg) {
+				t.Errorf("message %+v, want %+v", msg, tt.wantMsg)
+			}
+		})
+	}
+}
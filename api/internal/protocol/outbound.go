@@ -0,0 +1,150 @@
+package protocol
+
+type Envelope struct {
+	Version int         `json:"v"`
+	State   State       `json:"state"`
+	RoomID  string      `json:"roomID,omitempty"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func NewEnvelope(state State, roomID, message string, data interface{}) *Envelope {
+	return &Envelope{
+		Version: Version,
+		State:   state,
+		RoomID:  roomID,
+		Message: message,
+		Data:    data,
+	}
+}
+
+type PeriodData struct {
+	Moves int `json:"moves"` // 0 means the rest of the game
+	Time  int `json:"time"`  // seconds
+}
+
+type TimeControlData struct {
+	Name      string       `json:"name"`
+	Periods   []PeriodData `json:"periods"`
+	Increment int          `json:"increment"` // seconds
+	Delay     int          `json:"delay"`     // seconds
+	DelayMode string       `json:"delayMode,omitempty"`
+}
+
+type MatchedData struct {
+	Color        string           `json:"color"`
+	GameTime     int              `json:"gameTime"` // seconds
+	TimeControl  *TimeControlData `json:"timeControl"`
+	SessionToken string           `json:"sessionToken,omitempty"` // pass as ?resume= to reconnect
+}
+
+// ClockData is the state of both clocks at ServerTime. Clients count the
+// running clock down locally until the next update.
+type ClockData struct {
+	White      int64  `json:"white"`             // milliseconds left
+	Black      int64  `json:"black"`             // milliseconds left
+	Running    string `json:"running,omitempty"` // color whose clock is running
+	ServerTime int64  `json:"serverTime"`        // unix milliseconds
+}
+
+// SnapshotData is sent after a reconnect so the client can rebuild the game.
+type SnapshotData struct {
+	Color       string           `json:"color"`
+	GameTime    int              `json:"gameTime"` // seconds
+	TimeControl *TimeControlData `json:"timeControl"`
+	FEN         string           `json:"fen"`
+	Moves       []string         `json:"moves"`
+	WhiteTime   int              `json:"whiteTime"` // seconds left
+	BlackTime   int              `json:"blackTime"` // seconds left
+	Turn        string           `json:"turn"`      // color to move
+	Clock       *ClockData       `json:"clock"`
+}
+
+type ErrorData struct {
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+type CommandRejectedData struct {
+	Command string `json:"command"`
+	Code    string `json:"code"`
+	Reason  string `json:"reason"`
+}
+
+type MoveRejectedData struct {
+	Move   string `json:"move"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+type OpponentMoveData struct {
+	Move  string     `json:"move"`
+	Clock *ClockData `json:"clock"`
+}
+
+type YourTurnData struct {
+	Clock *ClockData `json:"clock"`
+	// ClaimableDraws lists the draws the player may claim instead of moving
+	ClaimableDraws []string `json:"claimableDraws,omitempty"`
+}
+
+type TimeLeftData struct {
+	Time         int    `json:"time"` // whole seconds
+	Milliseconds int64  `json:"ms"`
+	Color        string `json:"color"`
+}
+
+type OpponentDisconnectedData struct {
+	ReconnectGrace int `json:"reconnectGrace"` // seconds before the opponent forfeits
+}
+
+type GuessRejectedData struct {
+	Guess  string `json:"guess"`
+	Code   string `json:"code"`
+	Reason string `json:"reason"`
+}
+
+type GameEndedData struct {
+	Result string `json:"result"`
+	Reason string `json:"reason"`
+}
+
+type AIMeta struct {
+	Rank    int    `json:"rank"` // Elo the AI played at
+	Engine  string `json:"engine"`
+	Persona string `json:"persona,omitempty"`
+	// EngineElo and Strength tell how much of Rank came from limiting the
+	// engine, the rest came from the human error model
+	EngineElo int    `json:"engineElo,omitempty"`
+	Strength  string `json:"strength,omitempty"`
+}
+
+type VerdictData struct {
+	Guess   string  `json:"guess"`
+	Correct bool    `json:"correct"`
+	IsAI    bool    `json:"isAI"`
+	AIMeta  *AIMeta `json:"AIMeta,omitempty"` // only for AI opponents
+}
+
+// Outbound lists the data sent with every server state, nil means the state
+// carries no data.
+var Outbound = []struct {
+	State State
+	Data  interface{}
+}{
+	{StateMatched, MatchedData{}},
+	{StateResumed, SnapshotData{}},
+	{StateError, ErrorData{}},
+	{StateCommandRejected, CommandRejectedData{}},
+	{StateMoveRejected, MoveRejectedData{}},
+	{StateOpponentMove, OpponentMoveData{}},
+	{StateYourTurn, YourTurnData{}},
+	{StateTimeLeft, TimeLeftData{}},
+	{StateOpponentDisconnected, OpponentDisconnectedData{}},
+	{StateOpponentReconnected, nil},
+	{StateDrawOffered, nil},
+	{StateDrawDeclined, nil},
+	{StateGuessRejected, GuessRejectedData{}},
+	{StateGameEnded, GameEndedData{}},
+	{StateVerdict, VerdictData{}},
+}
@@ -0,0 +1,44 @@
+// Package protocol describes every message exchanged over the game websocket.
+//
+// Server messages are wrapped in an Envelope whose State tells the client how
+// to read Data. Client messages carry a Type instead and are decoded strictly,
+// unknown fields or a different protocol version are rejected.
+//
+//go:generate go run ../../cmd/schema -o schema.json
+package protocol
+
+// Version is bumped on every incompatible change to the messages.
+const Version = 1
+
+type State int
+
+const (
+	StateMatched              State = 1
+	StateResumed              State = 2
+	StateError                State = 70
+	StateCommandRejected      State = 76
+	StateMoveRejected         State = 77
+	StateOpponentMove         State = 78
+	StateYourTurn             State = 79
+	StateTimeLeft             State = 80
+	StateOpponentDisconnected State = 81
+	StateOpponentReconnected  State = 82
+	StateDrawOffered          State = 83
+	StateDrawDeclined         State = 84
+	StateGuessRejected        State = 98
+	StateGameEnded            State = 99
+	StateVerdict              State = 100
+)
+
+type InboundType string
+
+const (
+	TypeMove        InboundType = "move"
+	TypeGuess       InboundType = "guess"
+	TypeResign      InboundType = "resign"
+	TypeOfferDraw   InboundType = "offerDraw"
+	TypeAcceptDraw  InboundType = "acceptDraw"
+	TypeDeclineDraw InboundType = "declineDraw"
+	TypeAbort       InboundType = "abort"
+	TypeClaimDraw   InboundType = "claimDraw"
+)
@@ -0,0 +1,134 @@
+package protocol
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+var rawMessageType = reflect.TypeOf(json.RawMessage{})
+
+// Schema builds a JSON schema (draft 2020-12) of the protocol from the
+// message structs, so clients can be generated or validated against it.
+func Schema() map[string]interface{} {
+	defs := map[string]interface{}{}
+
+	outbound := make([]interface{}, 0, len(Outbound))
+	for _, msg := range Outbound {
+		properties := map[string]interface{}{
+			"v":       map[string]interface{}{"const": Version},
+			"state":   map[string]interface{}{"const": int(msg.State)},
+			"roomID":  map[string]interface{}{"type": "string"},
+			"message": map[string]interface{}{"type": "string"},
+		}
+		required := []string{"v", "state", "message"}
+
+		if msg.Data != nil {
+			properties["data"] = definition(defs, reflect.TypeOf(msg.Data))
+			required = append(required, "data")
+		}
+
+		outbound = append(outbound, map[string]interface{}{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		})
+	}
+
+	inbound := make([]interface{}, 0, len(InboundMessages))
+	for _, msg := range InboundMessages {
+		inbound = append(inbound, map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"v":    map[string]interface{}{"const": Version},
+				"type": map[string]interface{}{"const": string(msg.Type)},
+				"data": definition(defs, reflect.TypeOf(msg.Data)),
+			},
+			"required":             []string{"v", "type", "data"},
+			"additionalProperties": false,
+		})
+	}
+
+	defs["Outbound"] = map[string]interface{}{"oneOf": outbound}
+	defs["Inbound"] = map[string]interface{}{"oneOf": inbound}
+
+	return map[string]interface{}{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"title":   "stockfish-or-not websocket protocol",
+		"version": Version,
+		"$defs":   defs,
+	}
+}
+
+// definition registers named structs in defs and returns a reference to them,
+// any other type is described inline.
+func definition(defs map[string]interface{}, t reflect.Type) map[string]interface{} {
+	if t.Kind() == reflect.Struct {
+		if _, ok := defs[t.Name()]; !ok {
+			defs[t.Name()] = nil // guards against recursive types
+			defs[t.Name()] = structSchema(defs, t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+
+	return typeSchema(defs, t)
+}
+
+func structSchema(defs map[string]interface{}, t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = definition(defs, field.Type)
+		if !strings.Contains(opts, "omitempty") {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+func typeSchema(defs map[string]interface{}, t reflect.Type) map[string]interface{} {
+	if t == rawMessageType {
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return definition(defs, t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": definition(defs, t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": definition(defs, t.Elem())}
+	}
+
+	return map[string]interface{}{}
+}
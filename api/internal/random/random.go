@@ -0,0 +1,63 @@
+// Package random abstracts the random source, so matchmaking and the AI can
+// be replayed from a seed.
+package random
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+// Rand is the subset of math/rand/v2 the server draws from. Implementations
+// are safe for concurrent use.
+type Rand interface {
+	Float64() float64
+	IntN(n int) int
+	Int64N(n int64) int64
+	NormFloat64() float64
+}
+
+type global struct{}
+
+func (global) Float64() float64     { return rand.Float64() }
+func (global) IntN(n int) int       { return rand.IntN(n) }
+func (global) Int64N(n int64) int64 { return rand.Int64N(n) }
+func (global) NormFloat64() float64 { return rand.NormFloat64() }
+
+// Default draws from the randomly seeded global source.
+func Default() Rand {
+	return global{}
+}
+
+// Seeded draws the same numbers on every run with the same seed.
+type Seeded struct {
+	rand *rand.Rand
+	mux  sync.Mutex
+}
+
+func NewSeeded(seed uint64) *Seeded {
+	return &Seeded{rand: rand.New(rand.NewPCG(seed, seed))}
+}
+
+func (s *Seeded) Float64() float64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rand.Float64()
+}
+
+func (s *Seeded) IntN(n int) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rand.IntN(n)
+}
+
+func (s *Seeded) Int64N(n int64) int64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rand.Int64N(n)
+}
+
+func (s *Seeded) NormFloat64() float64 {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.rand.NormFloat64()
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/style77/stockfish-or-not/internal/engine"
+	"github.com/style77/stockfish-or-not/internal/game"
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+)
+
+// events handled by a room's event loop
+
+type moveEvent struct {
+	player *models.Player
+	move   string
+}
+
+// commandEvent is a resignation, draw offer, draw claim or abort, method is
+// the claimed draw
+type commandEvent struct {
+	player  *models.Player
+	command protocol.InboundType
+	method  string
+}
+
+type guessEvent struct {
+	player *models.Player
+	guess  string
+}
+
+type tickEvent struct {
+	player    *models.Player
+	remaining time.Duration
+}
+
+type flagEvent struct {
+	player *models.Player
+}
+
+type disconnectEvent struct {
+	player *models.Player
+	conn   *websocket.Conn
+}
+
+type resumeEvent struct {
+	player *models.Player
+	conn   *websocket.Conn
+	reply  chan error
+}
+
+type forfeitEvent struct {
+	player *models.Player
+}
+
+// aiSearchedEvent carries the engine's answer, start is when the AI began to
+// think about the move
+type aiSearchedEvent struct {
+	player *models.Player
+	result *engine.SearchResult
+	err    error
+	depth  int
+	start  time.Time
+}
+
+// aiDrawAnswerEvent answers the draw offer with offerID
+type aiDrawAnswerEvent struct {
+	player  *models.Player
+	offerID int
+	accepts bool
+	err     error
+}
+
+// closeEvent ends the guess window
+type closeEvent struct{}
+
+// post hands event to the room's event loop. It is dropped once the loop
+// exited, false is returned then.
+func post(room *models.Room, event interface{}) bool {
+	select {
+	case room.Events <- event:
+		return true
+	case <-room.Done:
+		return false
+	}
+}
+
+// startRoom starts the game once the players are set up and notified.
+func (app *App) startRoom(room *models.Room) {
+	go app.runRoom(room)
+}
+
+// runRoom is the room's event loop, it runs until the guess phase of the
+// ended game is over.
+func (app *App) runRoom(room *models.Room) {
+	defer close(room.Done)
+
+	room.State = models.RoomPlaying
+
+	// a player that left while being matched never got a disconnect handled
+	// by the room, they get the reconnect window now
+	for _, player := range []*models.Player{room.Player1, room.Player2} {
+		if isDisconnected(player) {
+			app.awaitReconnect(room, player)
+		}
+	}
+
+	// if the AI plays white it makes the first move
+	if room.Turn.AI != nil {
+		app.thinkAIMove(room, room.Turn, app.Config.AI.MaxDepth)
+	}
+
+	for event := range room.Events {
+		app.handleRoomEvent(room, event)
+
+		if room.State == models.RoomClosed {
+			return
+		}
+	}
+}
+
+func (app *App) handleRoomEvent(room *models.Room, event interface{}) {
+	switch event := event.(type) {
+	case moveEvent:
+		app.playMove(room, event.player, event.move)
+	case commandEvent:
+		app.runCommand(room, event)
+	case guessEvent:
+		app.guess(room, event.player, event.guess)
+	case tickEvent:
+		if room.State == models.RoomPlaying {
+			notifyPlayersAboutTime(room, *event.player.Color, event.remaining)
+		}
+	case flagEvent:
+		app.flag(room, event.player)
+	case disconnectEvent:
+		app.disconnect(room, event.player, event.conn)
+	case resumeEvent:
+		event.reply <- app.resume(room, event.player, event.conn)
+	case forfeitEvent:
+		app.forfeit(room, event.player)
+	case aiSearchedEvent:
+		app.playAIMove(room, event)
+	case aiDrawAnswerEvent:
+		app.answerAIDrawOffer(room, event)
+	case closeEvent:
+		app.closeRoom(room)
+	}
+}
+
+// closeRoom ends the guess phase, the game is archived only now, once, with
+// the guesses that were made.
+func (app *App) closeRoom(room *models.Room) {
+	room.State = models.RoomClosed
+
+	app.archiveGame(room)
+	game.CloseConnections(room)
+}
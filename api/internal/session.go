@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"errors"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/style77/stockfish-or-not/internal/game"
+	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/utils"
+)
+
+var (
+	ErrUnknownSession = errors.New("unknown session")
+	ErrSessionEnded   = errors.New("game has already ended")
+)
+
+// issueSession gives a matched human player a token to reconnect with.
+func (app *App) issueSession(player *models.Player) string {
+	token := uuid.New().String()
+
+	app.mux.Lock()
+	app.Sessions[token] = player
+	app.mux.Unlock()
+
+	player.SessionToken = token
+	return token
+}
+
+func (app *App) removeSessions(room *models.Room) {
+	app.mux.Lock()
+	defer app.mux.Unlock()
+
+	for token, player := range app.Sessions {
+		if player.Room == room {
+			delete(app.Sessions, token)
+		}
+	}
+}
+
+// playerRoom is the room the player plays in, nil while they are still
+// looking for an opponent.
+func playerRoom(player *models.Player) *models.Room {
+	player.Mux.Lock()
+	defer player.Mux.Unlock()
+
+	return player.Room
+}
+
+// ResumeSession reattaches a reconnected client to its player and sends it a
+// snapshot of the game.
+func (app *App) ResumeSession(token string, conn *websocket.Conn) (*models.Player, error) {
+	app.mux.Lock()
+	player, ok := app.Sessions[token]
+	app.mux.Unlock()
+
+	if !ok {
+		return nil, ErrUnknownSession
+	}
+
+	room := playerRoom(player)
+	if room == nil {
+		return nil, ErrSessionEnded
+	}
+
+	reply := make(chan error, 1)
+	if !post(room, resumeEvent{player: player, conn: conn, reply: reply}) {
+		return nil, ErrSessionEnded
+	}
+
+	if err := <-reply; err != nil {
+		return nil, err
+	}
+	return player, nil
+}
+
+func (app *App) resume(room *models.Room, player *models.Player, conn *websocket.Conn) error {
+	if room.State != models.RoomPlaying {
+		return ErrSessionEnded
+	}
+
+	player.Mux.Lock()
+	oldConn := player.Conn
+	player.Conn = conn
+	player.Disconnected = false
+	if player.ForfeitTimer != nil {
+		player.ForfeitTimer.Stop()
+		player.ForfeitTimer = nil
+	}
+	player.Mux.Unlock()
+
+	if oldConn != nil && oldConn != conn {
+		oldConn.Close()
+	}
+
+	log.Println("Player", conn.RemoteAddr(), "resumed game in room", room.ID)
+
+	err := utils.SafelyNotifyPlayer(player, protocol.NewEnvelope(protocol.StateResumed, room.ID, "Game resumed", game.Snapshot(room, player)))
+	if err != nil {
+		log.Println("Error sending game snapshot:", err)
+	}
+
+	err = utils.SafelyNotifyPlayer(getOpponent(room, player), protocol.NewEnvelope(protocol.StateOpponentReconnected, room.ID, "Opponent reconnected", nil))
+	if err != nil {
+		log.Println("Error notifying opponent about reconnect:", err)
+	}
+
+	return nil
+}
+
+// HandleDisconnect is called once conn stopped reading. A player that is
+// still looking for an opponent is dropped from matchmaking, a player in a
+// running game gets the reconnect grace window before forfeiting.
+func (app *App) HandleDisconnect(player *models.Player, conn *websocket.Conn) {
+	player.Mux.Lock()
+
+	// the player already reconnected on another connection
+	if player.Conn != conn {
+		player.Mux.Unlock()
+		return
+	}
+
+	room := player.Room
+	if room == nil {
+		player.Disconnected = true
+		player.Mux.Unlock()
+
+		log.Println("Player", conn.RemoteAddr(), "left while looking for an opponent")
+		removePlayerFromWaitingList(player, app)
+		return
+	}
+	player.Mux.Unlock()
+
+	post(room, disconnectEvent{player: player, conn: conn})
+}
+
+func (app *App) disconnect(room *models.Room, player *models.Player, conn *websocket.Conn) {
+	if room.State != models.RoomPlaying {
+		return
+	}
+
+	player.Mux.Lock()
+
+	// the player reconnected before the loop got to the disconnect
+	if player.Conn != conn {
+		player.Mux.Unlock()
+		return
+	}
+
+	player.Disconnected = true
+	player.Mux.Unlock()
+
+	log.Println("Player", conn.RemoteAddr(), "disconnected from room", room.ID)
+	app.awaitReconnect(room, player)
+}
+
+// awaitReconnect gives the disconnected player the reconnect grace window
+// before they forfeit.
+func (app *App) awaitReconnect(room *models.Room, player *models.Player) {
+	grace := app.Config.Game.ReconnectGrace.Duration
+
+	player.Mux.Lock()
+	player.ForfeitTimer = app.Clock.AfterFunc(grace, func() {
+		post(room, forfeitEvent{player: player})
+	})
+	player.Mux.Unlock()
+
+	log.Println("Waiting", grace, "for a player to reconnect to room", room.ID)
+
+	err := utils.SafelyNotifyPlayer(getOpponent(room, player), protocol.NewEnvelope(protocol.StateOpponentDisconnected, room.ID, "Opponent disconnected", &protocol.OpponentDisconnectedData{
+		ReconnectGrace: int(grace.Seconds()),
+	}))
+	if err != nil {
+		log.Println("Error notifying opponent about disconnect:", err)
+	}
+}
+
+func isDisconnected(player *models.Player) bool {
+	player.Mux.Lock()
+	defer player.Mux.Unlock()
+
+	return player.Disconnected
+}
+
+func getOpponent(room *models.Room, player *models.Player) *models.Player {
+	if room.Player1 == player {
+		return room.Player2
+	}
+	return room.Player1
+}
+
+func (app *App) forfeit(room *models.Room, player *models.Player) {
+	if room.State != models.RoomPlaying || !isDisconnected(player) {
+		return
+	}
+
+	log.Println("Player did not reconnect, forfeiting game in room", room.ID)
+
+	app.endGame(player, room, "abandoned", &utils.GameResult{
+		Outcome:       utils.WinFor(getOpponentColor(*player.Color)),
+		OutcomeReason: "abandoned",
+	})
+}
@@ -0,0 +1,148 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+)
+
+// FileStore appends every saved record as a JSON line to a file. Only where
+// each room's last line starts is kept in memory, records are read back from
+// the file when asked for.
+type FileStore struct {
+	file  *os.File
+	lines map[string]line
+	order []string
+	size  int64 // where the next record is appended
+	mux   sync.Mutex
+}
+
+type line struct {
+	offset int64
+	length int
+}
+
+func NewFileStore(path string) (*FileStore, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening game archive: %w", err)
+	}
+
+	s := &FileStore{file: file, lines: make(map[string]line)}
+	if err := s.index(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("reading game archive: %w", err)
+	}
+
+	return s, nil
+}
+
+// index finds the line of every room in the file, the last line written for
+// a room wins. A last line without its newline was cut short while being
+// appended and is dropped.
+func (s *FileStore) index() error {
+	reader := bufio.NewReader(s.file)
+
+	for {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) && len(data) > 0 {
+			log.Println("Dropping unfinished record at the end of the game archive")
+			return s.file.Truncate(s.size)
+		}
+
+		if len(data) > 0 {
+			var game struct {
+				RoomID string `json:"roomID"`
+			}
+			if err := json.Unmarshal(data, &game); err != nil {
+				return err
+			}
+
+			s.put(game.RoomID, line{offset: s.size, length: len(data)})
+			s.size += int64(len(data))
+		}
+
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// put records where the room's game is stored, the caller holds the lock.
+func (s *FileStore) put(roomID string, l line) {
+	if _, ok := s.lines[roomID]; !ok {
+		s.order = append(s.order, roomID)
+	}
+	s.lines[roomID] = l
+}
+
+func (s *FileStore) Save(game *GameRecord) error {
+	data, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, err := s.file.Write(data); err != nil {
+		return fmt.Errorf("writing game archive: %w", err)
+	}
+
+	s.put(game.RoomID, line{offset: s.size, length: len(data)})
+	s.size += int64(len(data))
+	return nil
+}
+
+func (s *FileStore) Get(roomID string) (*GameRecord, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	l, ok := s.lines[roomID]
+	if !ok {
+		return nil, ErrGameNotFound
+	}
+	return s.read(l)
+}
+
+func (s *FileStore) List() ([]*GameRecord, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	games := make([]*GameRecord, 0, len(s.order))
+	for _, roomID := range s.order {
+		game, err := s.read(s.lines[roomID])
+		if err != nil {
+			return nil, err
+		}
+		games = append(games, game)
+	}
+	return games, nil
+}
+
+// read loads the record on the line, the caller holds the lock.
+func (s *FileStore) read(l line) (*GameRecord, error) {
+	data := make([]byte, l.length)
+	if _, err := s.file.ReadAt(data, l.offset); err != nil {
+		return nil, fmt.Errorf("reading game archive: %w", err)
+	}
+
+	var game GameRecord
+	if err := json.Unmarshal(data, &game); err != nil {
+		return nil, fmt.Errorf("reading game archive: %w", err)
+	}
+	return &game, nil
+}
+
+func (s *FileStore) Close() error {
+	return s.file.Close()
+}
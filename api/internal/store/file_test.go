@@ -0,0 +1,96 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStoreReadsGamesBackFromDisk(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.jsonl")
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, game := range []*GameRecord{
+		{RoomID: "a", Reason: "resignation"},
+		{RoomID: "b", Reason: "Checkmate"},
+		{RoomID: "a", Reason: "aborted"},
+	} {
+		if err := s.Save(game); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	game, err := s.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if game.Reason != "aborted" {
+		t.Errorf("game a ended by %q, want the last saved aborted", game.Reason)
+	}
+
+	if _, err := s.Get("c"); err != ErrGameNotFound {
+		t.Errorf("getting unknown game: %v, want ErrGameNotFound", err)
+	}
+
+	games, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || games[0].RoomID != "a" || games[1].RoomID != "b" {
+		t.Errorf("listed %d games, want a and b in order", len(games))
+	}
+}
+
+func TestFileStoreDropsUnfinishedLastLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "games.jsonl")
+
+	s, err := NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(&GameRecord{RoomID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	// the server stopped in the middle of appending the next game
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"roomID":"b","mov`)
+	file.Close()
+
+	s, err = NewFileStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Save(&GameRecord{RoomID: "c"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Get("b"); err != ErrGameNotFound {
+		t.Errorf("getting unfinished game: %v, want ErrGameNotFound", err)
+	}
+
+	games, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(games) != 2 || games[0].RoomID != "a" || games[1].RoomID != "c" {
+		t.Errorf("listed %d games, want a and c", len(games))
+	}
+}
@@ -0,0 +1,57 @@
+package store
+
+import "sync"
+
+type MemoryStore struct {
+	games map[string]*GameRecord
+	order []string
+	mux   sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		games: make(map[string]*GameRecord),
+		order: make([]string, 0),
+	}
+}
+
+func (s *MemoryStore) Save(game *GameRecord) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.put(game)
+	return nil
+}
+
+func (s *MemoryStore) put(game *GameRecord) {
+	if _, ok := s.games[game.RoomID]; !ok {
+		s.order = append(s.order, game.RoomID)
+	}
+	s.games[game.RoomID] = game
+}
+
+func (s *MemoryStore) Get(roomID string) (*GameRecord, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	game, ok := s.games[roomID]
+	if !ok {
+		return nil, ErrGameNotFound
+	}
+	return game, nil
+}
+
+func (s *MemoryStore) List() ([]*GameRecord, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	games := make([]*GameRecord, 0, len(s.order))
+	for _, roomID := range s.order {
+		games = append(games, s.games[roomID])
+	}
+	return games, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
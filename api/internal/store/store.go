@@ -0,0 +1,49 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrGameNotFound = errors.New("game not found")
+
+type PlayerRecord struct {
+	IsAI  bool   `json:"isAI"`
+	Guess string `json:"guess,omitempty"` // human players only
+}
+
+type GameRecord struct {
+	RoomID    string       `json:"roomID"`
+	Moves     []string     `json:"moves"`
+	MoveTimes []int64      `json:"moveTimes"` // milliseconds spent on each move
+	White     PlayerRecord `json:"white"`
+	Black     PlayerRecord `json:"black"`
+	IsAI      bool         `json:"isAI"`
+	Result    string       `json:"result"`
+	Method    string       `json:"method"`
+	Reason    string       `json:"reason"`
+	GameTime  int          `json:"gameTime"` // seconds
+
+	// PGN TimeControl notation, older records only have GameTime
+	TimeControl string `json:"timeControl,omitempty"`
+
+	// ai games only
+	AIElo        int    `json:"aiElo,omitempty"`
+	AIPersona    string `json:"aiPersona,omitempty"`
+	AIEngineElo  int    `json:"aiEngineElo,omitempty"` // strength the engine itself was limited to
+	AIStrength   string `json:"aiStrength,omitempty"`  // how the engine was limited
+	AISkillLevel int    `json:"aiSkillLevel,omitempty"`
+	Engine       string `json:"engine,omitempty"`
+
+	StartedAt time.Time `json:"startedAt"`
+	EndedAt   time.Time `json:"endedAt"`
+}
+
+// GameStore archives finished games. Saving a record with an already known
+// RoomID replaces the previous one.
+type GameStore interface {
+	Save(game *GameRecord) error
+	Get(roomID string) (*GameRecord, error)
+	List() ([]*GameRecord, error)
+	Close() error
+}
@@ -0,0 +1,68 @@
+package timer
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+type DelayMode string
+
+const (
+	DelayNone DelayMode = ""
+	// DelaySimple doesn't start the clock until the delay has passed.
+	DelaySimple DelayMode = "simple"
+	// DelayBronstein gives back the time spent on a move, up to the delay.
+	DelayBronstein DelayMode = "bronstein"
+)
+
+// Period is a stage of the time control, its time is added to the clock when
+// the period begins. Moves is the number of moves to play within the period,
+// zero means the rest of the game.
+type Period struct {
+	Moves int
+	Time  time.Duration
+}
+
+type TimeControl struct {
+	Name      string
+	Periods   []Period
+	Increment time.Duration // Fischer increment, added after every move
+	Delay     time.Duration
+	DelayMode DelayMode
+}
+
+func SuddenDeath(duration time.Duration) TimeControl {
+	return TimeControl{
+		Periods: []Period{{Time: duration}},
+	}
+}
+
+// Initial is the time on the clock when the game starts.
+func (tc TimeControl) Initial() time.Duration {
+	if len(tc.Periods) == 0 {
+		return 0
+	}
+	return tc.Periods[0].Time
+}
+
+// String formats the time control like the PGN TimeControl tag, for example
+// "40/5400:1800+30". Delays have no PGN notation and are left out.
+func (tc TimeControl) String() string {
+	parts := make([]string, 0, len(tc.Periods))
+
+	for _, period := range tc.Periods {
+		part := strconv.Itoa(int(period.Time.Seconds()))
+		if period.Moves > 0 {
+			part = strconv.Itoa(period.Moves) + "/" + part
+		}
+		parts = append(parts, part)
+	}
+
+	result := strings.Join(parts, ":")
+	if tc.Increment > 0 {
+		result += "+" + strconv.Itoa(int(tc.Increment.Seconds()))
+	}
+
+	return result
+}
@@ -1,100 +1,240 @@
 package timer
 
 import (
-	"log"
 	"sync"
 	"time"
+
+	"github.com/style77/stockfish-or-not/internal/clock"
 )
 
+// Timer is a player's chess clock. Time is charged from monotonic timestamps
+// taken when the turn starts and when the move is made, flag fall is fired by
+// a single timer armed for the exact moment the clock runs out.
 type Timer struct {
-	Duration  int
-	IsRunning bool
-	IsPaused  bool
-	IsOver    bool
 	IsStarted bool
-	mux       sync.Mutex
+	IsOver    bool
+	Control   TimeControl
 
-	Stop         chan struct{}
-	Pause        chan struct{}
-	Resume       chan struct{}
-	TickCallback func(int)
+	remaining   time.Duration
+	running     bool
+	turnStart   time.Time
+	clock       clock.Clock
+	flag        clock.Timer
+	turn        int // invalidates flag timers armed for earlier turns
+	period      int // index of the current period in Control.Periods
+	periodMoves int // moves made within the current period
+	mux         sync.Mutex
+
+	tickInterval time.Duration
+	onTick       func(time.Duration)
+	onFlag       func()
+	stop         chan struct{}
 }
 
-func NewTimer(duration int, tickCallback func(int)) *Timer {
+// NewTimer creates a clock for the time control that measures time with clk.
+// onFlag is called once the time runs out. onTick is optional and gets the
+// remaining time every tickInterval while the clock is running, a zero
+// interval disables it.
+func NewTimer(clk clock.Clock, control TimeControl, tickInterval time.Duration, onTick func(time.Duration), onFlag func()) *Timer {
 	return &Timer{
-		Duration:     duration,
-		IsRunning:    false,
-		IsPaused:     false,
-		IsOver:       false,
-		IsStarted:    false,
-		Stop:         make(chan struct{}),
-		Pause:        make(chan struct{}),
-		Resume:       make(chan struct{}),
-		TickCallback: tickCallback,
+		Control:      control,
+		clock:        clk,
+		remaining:    control.Initial(),
+		tickInterval: tickInterval,
+		onTick:       onTick,
+		onFlag:       onFlag,
+		stop:         make(chan struct{}),
 	}
 }
 
 func (t *Timer) StartTimer() {
 	t.mux.Lock()
-	t.IsRunning = true
-	t.IsPaused = false
-	t.IsOver = false
+	defer t.mux.Unlock()
+
+	if t.IsStarted || t.IsOver {
+		return
+	}
+
 	t.IsStarted = true
-	t.mux.Unlock()
+	t.startTurn()
 
-	go func() {
-		for {
-			t.mux.Lock()
-			if t.IsOver {
-				t.mux.Unlock()
-				break
-			}
+	if t.tickInterval > 0 && t.onTick != nil {
+		go t.tick()
+	}
+}
 
-			if t.IsPaused {
-				t.mux.Unlock()
-				time.Sleep(100 * time.Millisecond)
-				continue
-			}
+func (t *Timer) ResumeTimer() {
+	t.mux.Lock()
+	defer t.mux.Unlock()
 
-			t.Duration--
+	if !t.running && !t.IsOver {
+		t.startTurn()
+	}
+}
 
-			if t.Duration <= 0 {
-				t.IsOver = true
-				t.IsRunning = false
-				t.mux.Unlock()
-				t.TickCallback(0)
-				log.Println("Timer is over")
+// PauseTimer stops the clock without treating it as a completed move.
+func (t *Timer) PauseTimer() {
+	t.mux.Lock()
+	defer t.mux.Unlock()
 
-				break
-			}
+	if t.running {
+		t.charge(t.clock.Since(t.turnStart))
+	}
+}
 
-			t.TickCallback(t.Duration)
-			t.mux.Unlock()
-			time.Sleep(1 * time.Second)
+// CompleteMove stops the clock once the player made a move, credits the time
+// the control gives back for it and returns the time spent on the move.
+// flagged reports that the time ran out before the move, the move doesn't
+// count then and the caller ends the game on time.
+func (t *Timer) CompleteMove() (spent time.Duration, flagged bool) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+
+	if t.IsOver {
+		return 0, true
+	}
+
+	if t.running {
+		spent = t.clock.Since(t.turnStart)
+
+		// the flag timer hasn't fired yet, but the time is up all the same
+		if spent > t.remaining+t.delay() {
+			t.running = false
+			t.flag.Stop()
+			t.remaining = 0
+			t.IsOver = true
+			close(t.stop)
+			return spent, true
 		}
-	}()
+
+		t.charge(spent)
+	}
+
+	t.remaining += t.Control.Increment
+
+	if t.Control.DelayMode == DelayBronstein {
+		t.remaining += min(spent, t.Control.Delay)
+	}
+
+	// moving on to the next period adds its time to the clock
+	t.periodMoves++
+	periods := t.Control.Periods
+	if t.period+1 < len(periods) && periods[t.period].Moves > 0 && t.periodMoves >= periods[t.period].Moves {
+		t.period++
+		t.periodMoves = 0
+		t.remaining += periods[t.period].Time
+	}
+
+	return spent, false
 }
 
-func (t *Timer) StopTimer() {
-	close(t.Stop)
+func (t *Timer) Remaining() time.Duration {
 	t.mux.Lock()
-	t.IsRunning = false
-	t.IsOver = true
-	t.mux.Unlock()
+	defer t.mux.Unlock()
+
+	return t.current()
 }
 
-func (t *Timer) PauseTimer() {
+// Running reports whether the clock is counting down right now.
+func (t *Timer) Running() bool {
 	t.mux.Lock()
-	t.IsPaused = true
-	t.mux.Unlock()
+	defer t.mux.Unlock()
+
+	return t.running
 }
 
-func (t *Timer) ResumeTimer() {
+func (t *Timer) StopTimer() {
 	t.mux.Lock()
-	t.IsPaused = false
+	defer t.mux.Unlock()
+
+	if t.running {
+		t.charge(t.clock.Since(t.turnStart))
+	}
+
+	if !t.IsOver {
+		t.IsOver = true
+		close(t.stop)
+	}
+}
+
+// startTurn starts the clock and arms the flag, the caller holds the lock.
+func (t *Timer) startTurn() {
+	t.running = true
+	t.turnStart = t.clock.Now()
+	t.turn++
+
+	turn := t.turn
+	t.flag = t.clock.AfterFunc(t.delay()+t.remaining, func() {
+		t.flagFall(turn)
+	})
+}
+
+// charge stops the clock and takes the time spent off it, the caller holds
+// the lock.
+func (t *Timer) charge(spent time.Duration) {
+	t.running = false
+	t.flag.Stop()
+
+	if spent > t.delay() {
+		t.remaining -= spent - t.delay()
+	}
+	t.remaining = max(t.remaining, 0)
+}
+
+// current is the time left right now, the caller holds the lock.
+func (t *Timer) current() time.Duration {
+	if !t.running {
+		return t.remaining
+	}
+
+	charged := t.clock.Since(t.turnStart) - t.delay()
+	if charged <= 0 {
+		return t.remaining
+	}
+	return max(t.remaining-charged, 0)
+}
+
+// delay is the part of every move the clock doesn't run for.
+func (t *Timer) delay() time.Duration {
+	if t.Control.DelayMode == DelaySimple {
+		return t.Control.Delay
+	}
+	return 0
+}
+
+func (t *Timer) flagFall(turn int) {
+	t.mux.Lock()
+	if !t.running || t.turn != turn || t.IsOver {
+		t.mux.Unlock()
+		return
+	}
+
+	t.running = false
+	t.remaining = 0
+	t.IsOver = true
+	close(t.stop)
 	t.mux.Unlock()
+
+	t.onFlag()
 }
 
-func (t *Timer) Close() {
-	close(t.Stop)
+func (t *Timer) tick() {
+	ticker := t.clock.NewTicker(t.tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			t.mux.Lock()
+			running := t.running
+			remaining := t.current()
+			t.mux.Unlock()
+
+			if running {
+				t.onTick(remaining)
+			}
+		case <-t.stop:
+			return
+		}
+	}
 }
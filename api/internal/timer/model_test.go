@@ -0,0 +1,179 @@
+package timer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/style77/stockfish-or-not/internal/clock"
+	"github.com/style77/stockfish-or-not/internal/timer"
+)
+
+func newTimer(control timer.TimeControl) (*timer.Timer, *clock.Fake, chan struct{}) {
+	fake := clock.NewFake(time.Unix(0, 0))
+	flagged := make(chan struct{}, 1)
+
+	t := timer.NewTimer(fake, control, 0, nil, func() { flagged <- struct{}{} })
+	return t, fake, flagged
+}
+
+func TestFlagFallsWhenTimeRunsOut(t *testing.T) {
+	clk, fake, flagged := newTimer(timer.SuddenDeath(10 * time.Second))
+	clk.StartTimer()
+
+	fake.Advance(9 * time.Second)
+	if remaining := clk.Remaining(); remaining != time.Second {
+		t.Errorf("remaining = %s, want 1s", remaining)
+	}
+
+	select {
+	case <-flagged:
+		t.Fatal("flag fell with time left")
+	default:
+	}
+
+	fake.Advance(time.Second)
+
+	select {
+	case <-flagged:
+	case <-time.After(time.Second):
+		t.Fatal("flag did not fall")
+	}
+
+	if clk.Remaining() != 0 || !clk.IsOver {
+		t.Errorf("remaining = %s and over = %v after flag fall", clk.Remaining(), clk.IsOver)
+	}
+}
+
+func TestCompleteMoveCreditsIncrement(t *testing.T) {
+	control := timer.SuddenDeath(10 * time.Second)
+	control.Increment = 2 * time.Second
+
+	clk, fake, _ := newTimer(control)
+	clk.StartTimer()
+
+	fake.Advance(3 * time.Second)
+	if spent, _ := clk.CompleteMove(); spent != 3*time.Second {
+		t.Errorf("spent = %s, want 3s", spent)
+	}
+
+	if remaining := clk.Remaining(); remaining != 9*time.Second {
+		t.Errorf("remaining = %s, want 9s", remaining)
+	}
+
+	// the clock is stopped until the next turn
+	fake.Advance(time.Minute)
+	if remaining := clk.Remaining(); remaining != 9*time.Second {
+		t.Errorf("remaining = %s while stopped, want 9s", remaining)
+	}
+}
+
+func TestSimpleDelay(t *testing.T) {
+	control := timer.SuddenDeath(10 * time.Second)
+	control.Delay = 2 * time.Second
+	control.DelayMode = timer.DelaySimple
+
+	clk, fake, _ := newTimer(control)
+	clk.StartTimer()
+
+	fake.Advance(1500 * time.Millisecond)
+	clk.CompleteMove()
+	if remaining := clk.Remaining(); remaining != 10*time.Second {
+		t.Errorf("remaining = %s after a move within the delay, want 10s", remaining)
+	}
+
+	clk.ResumeTimer()
+	fake.Advance(3 * time.Second)
+	clk.CompleteMove()
+	if remaining := clk.Remaining(); remaining != 9*time.Second {
+		t.Errorf("remaining = %s, want 9s", remaining)
+	}
+}
+
+func TestBronsteinDelay(t *testing.T) {
+	control := timer.SuddenDeath(10 * time.Second)
+	control.Delay = 2 * time.Second
+	control.DelayMode = timer.DelayBronstein
+
+	clk, fake, _ := newTimer(control)
+	clk.StartTimer()
+
+	fake.Advance(5 * time.Second)
+	clk.CompleteMove()
+	if remaining := clk.Remaining(); remaining != 7*time.Second {
+		t.Errorf("remaining = %s, want 7s", remaining)
+	}
+}
+
+func TestNextPeriodAddsTime(t *testing.T) {
+	control := timer.TimeControl{Periods: []timer.Period{{Moves: 2, Time: 10 * time.Second}, {Time: 5 * time.Second}}}
+
+	clk, fake, _ := newTimer(control)
+	clk.StartTimer()
+
+	for i := 0; i < 2; i++ {
+		clk.ResumeTimer()
+		fake.Advance(time.Second)
+		clk.CompleteMove()
+	}
+
+	if remaining := clk.Remaining(); remaining != 13*time.Second {
+		t.Errorf("remaining = %s, want 13s", remaining)
+	}
+}
+
+func TestStopTimerTwice(t *testing.T) {
+	clk, fake, flagged := newTimer(timer.SuddenDeath(time.Second))
+	clk.StartTimer()
+
+	clk.StopTimer()
+	clk.StopTimer()
+
+	fake.Advance(time.Minute)
+
+	select {
+	case <-flagged:
+		t.Error("flag fell on a stopped clock")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestMoveAfterTimeRanOutIsFlagged(t *testing.T) {
+	control := timer.SuddenDeath(10 * time.Second)
+	control.Increment = 2 * time.Second
+
+	clk, fake, _ := newTimer(control)
+	clk.StartTimer()
+
+	// the move arrives after the flag fell or just before its timer fired,
+	// either way it doesn't count and earns no increment
+	fake.Advance(15 * time.Second)
+	if _, flagged := clk.CompleteMove(); !flagged {
+		t.Error("late move was not flagged")
+	}
+
+	if clk.Remaining() != 0 || !clk.IsOver {
+		t.Errorf("remaining = %s and over = %v after a late move", clk.Remaining(), clk.IsOver)
+	}
+
+	if _, flagged := clk.CompleteMove(); !flagged {
+		t.Error("move after the flag fell was not flagged")
+	}
+}
+
+func TestMoveWithinSimpleDelayIsInTime(t *testing.T) {
+	control := timer.SuddenDeath(time.Second)
+	control.Delay = 2 * time.Second
+	control.DelayMode = timer.DelaySimple
+
+	clk, fake, _ := newTimer(control)
+	clk.StartTimer()
+
+	fake.Advance(2500 * time.Millisecond)
+	if _, flagged := clk.CompleteMove(); flagged {
+		t.Error("move within delay and remaining time was flagged")
+	}
+
+	if remaining := clk.Remaining(); remaining != 500*time.Millisecond {
+		t.Errorf("remaining = %s, want 500ms", remaining)
+	}
+}
@@ -0,0 +1,71 @@
+package utils
+
+import (
+	"github.com/notnil/chess"
+)
+
+// HasMatingMaterial reports whether color could still checkmate by any
+// series of legal moves, however unlikely. It decides games lost on time,
+// where FIDE rules draw the game if the opponent cannot possibly mate.
+func HasMatingMaterial(board *chess.Board, color chess.Color) bool {
+	var knights, lightBishops, darkBishops int
+	opponentBlockers := 0 // opponent pieces that could block their own king
+
+	for square, piece := range board.SquareMap() {
+		if piece.Color() != color {
+			if piece.Type() != chess.King {
+				opponentBlockers++
+			}
+			continue
+		}
+
+		switch piece.Type() {
+		case chess.Pawn, chess.Rook, chess.Queen:
+			return true
+		case chess.Knight:
+			knights++
+		case chess.Bishop:
+			if isLightSquare(square) {
+				lightBishops++
+			} else {
+				darkBishops++
+			}
+		}
+	}
+
+	bishops := lightBishops + darkBishops
+
+	switch {
+	case knights == 0 && bishops == 0:
+		return false
+	case knights == 1 && bishops == 0:
+		// a lone knight only mates a king hemmed in by its own pieces
+		return opponentBlockers > 0
+	case knights == 0 && (lightBishops == 0 || darkBishops == 0):
+		// bishops on a single color need an opponent piece that can stand on
+		// the other color
+		return hasBlockerOffColor(board, color, lightBishops > 0)
+	}
+
+	return true
+}
+
+// hasBlockerOffColor reports whether color's opponent has a piece that can
+// reach squares of the other color than the bishops on light (or dark)
+// squares.
+func hasBlockerOffColor(board *chess.Board, color chess.Color, light bool) bool {
+	for square, piece := range board.SquareMap() {
+		if piece.Color() == color || piece.Type() == chess.King {
+			continue
+		}
+
+		if piece.Type() != chess.Bishop || isLightSquare(square) != light {
+			return true
+		}
+	}
+	return false
+}
+
+func isLightSquare(square chess.Square) bool {
+	return (int(square.File())+int(square.Rank()))%2 == 1
+}
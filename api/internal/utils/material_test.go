@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/notnil/chess"
+)
+
+func TestHasMatingMaterial(t *testing.T) {
+	tests := []struct {
+		name string
+		fen  string
+		want bool
+	}{
+		{"bare kings", "8/8/8/4k3/8/8/8/4K3 w - - 0 1", false},
+		{"knight vs king", "8/8/8/4k3/8/8/8/4KN2 w - - 0 1", false},
+		{"knight vs king and pawn", "8/4p3/8/4k3/8/8/8/4KN2 w - - 0 1", true},
+		{"bishop vs king", "8/8/8/4k3/8/8/8/2B1K3 w - - 0 1", false},
+		{"same color bishops", "5b2/8/8/4k3/8/8/8/2B1K3 w - - 0 1", false},
+		{"opposite color bishops", "2b5/8/8/4k3/8/8/8/2B1K3 w - - 0 1", true},
+		{"bishop and knight", "8/8/8/4k3/8/8/8/2B1KN2 w - - 0 1", true},
+		{"pawn", "8/8/8/4k3/8/8/4P3/4K3 w - - 0 1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fen, err := chess.FEN(tt.fen)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			board := chess.NewGame(fen).Position().Board()
+			if got := HasMatingMaterial(board, chess.White); got != tt.want {
+				t.Errorf("HasMatingMaterial = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
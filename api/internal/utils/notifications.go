@@ -2,15 +2,29 @@ package utils
 
 import (
 	"log"
+	"time"
 
 	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
 )
 
-func SafelyNotifyPlayer(player *models.Player, data map[string]interface{}) error {
-	if player.Conn != nil {
+// messages are sent from the room's event loop, a client that doesn't take
+// one within writeTimeout holds up its room no longer than that
+const writeTimeout = 5 * time.Second
+
+// SafelyNotifyPlayer sends data to the player. A connection that fails to
+// take it is closed, so its reader counts the player as disconnected.
+func SafelyNotifyPlayer(player *models.Player, data *protocol.Envelope) error {
+	player.Mux.Lock()
+	defer player.Mux.Unlock()
+
+	if player.Conn != nil && !player.Disconnected {
+		player.Conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+
 		err := player.Conn.WriteJSON(data)
 		if err != nil {
 			log.Println("Error notifying player:", err)
+			player.Conn.Close()
 		}
 		return err
 	} else {
@@ -20,7 +34,7 @@ func SafelyNotifyPlayer(player *models.Player, data map[string]interface{}) erro
 	return nil
 }
 
-func NotifyBothPlayers(room *models.Room, message map[string]interface{}) error {
+func NotifyBothPlayers(room *models.Room, message *protocol.Envelope) error {
 	if err := SafelyNotifyPlayer(room.Player1, message); err != nil {
 		// log.Println("Error notifying player 1:", err)
 	}
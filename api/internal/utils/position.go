@@ -1,40 +1,36 @@
 package utils
 
 import (
-	"log"
-
 	"github.com/notnil/chess"
 )
 
-func GetPosition(moves []string) string {
-	position := ""
-	for _, move := range moves {
-		position += move + " "
+// WinFor returns the outcome of a game won by the player with the given color.
+func WinFor(color string) chess.Outcome {
+	if color == "white" {
+		return chess.WhiteWon
 	}
-
-	return position
+	return chess.BlackWon
 }
 
 type GameResult struct {
 	Outcome       chess.Outcome
 	OutcomeReason string
+	// Method is recorded on the board for games the board can't end on its
+	// own, e.g. chess.Resignation or chess.DrawOffer
+	Method chess.Method
 }
 
-func CheckEndGameStates(moves []string) (*GameResult, bool) {
-	board := chess.NewGame(chess.UseNotation(chess.UCINotation{}))
-
-	for _, move := range moves {
-		if err := board.MoveStr(move); err != nil {
-			log.Printf("Error applying move %s: %v", move, err)
-			continue
-		}
-	}
-
+// CheckEndGameStates reports the outcomes the board reaches on its own:
+// checkmate, stalemate, insufficient material, fivefold repetition and the
+// seventy-five-move rule. Threefold repetition and the fifty-move rule only
+// end the game when claimed.
+func CheckEndGameStates(board *chess.Game) (*GameResult, bool) {
 	gameEnded := board.Outcome() != chess.NoOutcome
 
 	result := GameResult{
 		Outcome:       board.Outcome(),
 		OutcomeReason: board.Method().String(),
+		Method:        board.Method(),
 	}
 
 	return &result, gameEnded
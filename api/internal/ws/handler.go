@@ -3,10 +3,13 @@ package ws
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/style77/stockfish-or-not/internal"
 	"github.com/style77/stockfish-or-not/internal/models"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/utils"
 )
 
 var upgrader = websocket.Upgrader{
@@ -23,21 +26,94 @@ func HandleConnections(w http.ResponseWriter, r *http.Request, app *internal.App
 	}
 	defer conn.Close()
 
-	player := &models.Player{Conn: conn, IsAI: false}
+	var player *models.Player
 
-	go app.FindOpponent(player)
+	if token := r.URL.Query().Get("resume"); token != "" {
+		player, err = app.ResumeSession(token, conn)
+		if err != nil {
+			log.Println("Error resuming session:", err)
+			conn.WriteJSON(protocol.NewEnvelope(protocol.StateError, "", "Could not resume game", &protocol.ErrorData{
+				Code:   "invalid_session",
+				Reason: err.Error(),
+			}))
+			return
+		}
+	} else {
+		player = &models.Player{Conn: conn, IsAI: false}
+
+		go app.FindOpponent(player)
+	}
+
+	stopHeartbeat := startHeartbeat(conn, app.Config.Connection.PingInterval.Duration, app.Config.Connection.PongTimeout.Duration)
+	defer stopHeartbeat()
 
 	for {
-		var msg map[string]interface{}
-		err := conn.ReadJSON(&msg)
+		_, data, err := conn.ReadMessage()
 		if err != nil {
-			log.Println("Error reading JSON:", err)
+			log.Println("Error reading message:", err)
 			break
 		}
 
-		log.Println("Received message:", msg)
-		if move, ok := msg["move"].(string); ok {
-			go app.ProcessMove(player, move, msg["isFirstMove"].(bool))
+		log.Println("Received message:", string(data))
+
+		msgType, msg, decodeErr := protocol.Decode(data)
+		if decodeErr != nil {
+			log.Println("Rejected message:", decodeErr)
+			utils.SafelyNotifyPlayer(player, protocol.NewEnvelope(protocol.StateError, "", "Invalid message", &protocol.ErrorData{
+				Code:   decodeErr.Code,
+				Reason: decodeErr.Reason,
+			}))
+			continue
+		}
+
+		switch msgType {
+		case protocol.TypeMove:
+			move := msg.(*protocol.MoveMessage)
+			app.ProcessMove(player, move.Move, move.IsFirstMove)
+		case protocol.TypeGuess:
+			guess := msg.(*protocol.GuessMessage)
+			app.ProcessGuess(player, guess.Guess)
+		case protocol.TypeResign, protocol.TypeOfferDraw, protocol.TypeAcceptDraw, protocol.TypeDeclineDraw, protocol.TypeAbort:
+			app.ProcessCommand(player, msgType)
+		case protocol.TypeClaimDraw:
+			claim := msg.(*protocol.ClaimDrawMessage)
+			app.ProcessDrawClaim(player, claim.Method)
 		}
 	}
+
+	// the room may be busy for a moment, the connection is closed right away
+	conn.Close()
+	app.HandleDisconnect(player, conn)
+}
+
+// startHeartbeat pings the client periodically, a connection that doesn't
+// answer within pongTimeout fails its next read and counts as disconnected.
+func startHeartbeat(conn *websocket.Conn, pingInterval, pongTimeout time.Duration) func() {
+	conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongTimeout))
+	})
+
+	done := make(chan struct{})
+	ticker := time.NewTicker(pingInterval)
+
+	go func() {
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingInterval)); err != nil {
+					log.Println("Error sending ping:", err)
+					return
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
 }
@@ -0,0 +1,344 @@
+package ws_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/style77/stockfish-or-not/internal/clock"
+	"github.com/style77/stockfish-or-not/internal/config"
+	"github.com/style77/stockfish-or-not/internal/game"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/random"
+)
+
+var foolsMate = []string{"f2f3", "e7e5", "g2g4", "d8h4"}
+
+func humansOnly(cfg *config.Config) {
+	cfg.Matchmaking.AIProbability = 0
+	cfg.Matchmaking.OpponentTimeout = config.Duration{Duration: time.Minute}
+}
+
+func aiOnly(cfg *config.Config) {
+	cfg.Matchmaking.AIProbability = 1
+}
+
+func TestHumansPlayToCheckmate(t *testing.T) {
+	h := newHarness(t, nil, humansOnly)
+	white, black := h.pairHumans()
+
+	players := []*client{white, black}
+	for i, move := range foolsMate {
+		mover, opponent := players[i%2], players[(i+1)%2]
+
+		mover.move(move)
+		if got := opponent.opponentMove(); got != move {
+			t.Fatalf("opponent saw move %s, want %s", got, move)
+		}
+	}
+
+	for _, player := range players {
+		ended := player.gameEnded()
+		if ended.Result != "0-1" || ended.Reason != "Checkmate" {
+			t.Errorf("game ended %s by %q, want 0-1 by checkmate", ended.Result, ended.Reason)
+		}
+	}
+}
+
+func TestIllegalMoveIsRejected(t *testing.T) {
+	h := newHarness(t, nil, humansOnly)
+	white, black := h.pairHumans()
+
+	var rejected protocol.MoveRejectedData
+
+	white.move("e2e5")
+	white.expect(protocol.StateMoveRejected, &rejected)
+	if rejected.Move != "e2e5" {
+		t.Errorf("rejected move %s, want e2e5", rejected.Move)
+	}
+
+	black.move("e7e5")
+	black.expect(protocol.StateMoveRejected, &rejected)
+	if rejected.Move != "e7e5" {
+		t.Errorf("rejected move %s, want e7e5", rejected.Move)
+	}
+
+	white.move("e2e4")
+	if got := black.opponentMove(); got != "e2e4" {
+		t.Errorf("black saw move %s, want e2e4", got)
+	}
+}
+
+func TestPlayerLosesOnTime(t *testing.T) {
+	h := newHarness(t, nil, func(cfg *config.Config) {
+		humansOnly(cfg)
+		cfg.Game.Time = config.Duration{Duration: time.Second}
+	})
+	white, black := h.pairHumans()
+
+	white.move("e2e4")
+	black.opponentMove()
+
+	// black never answers
+	for _, player := range []*client{white, black} {
+		ended := player.gameEnded()
+		if ended.Result != "1-0" || ended.Reason != "Time is up" {
+			t.Errorf("game ended %s by %q, want 1-0 on time", ended.Result, ended.Reason)
+		}
+	}
+}
+
+func TestAIGameToCheckmate(t *testing.T) {
+	h := newHarness(t, foolsMate, aiOnly)
+	player := h.connect()
+
+	color := player.matched()
+
+	// the AI plays its side of the script, the player the other
+	for i, move := range foolsMate {
+		if (i%2 == 0) == (color == "white") {
+			if i > 0 {
+				player.expect(protocol.StateYourTurn, nil)
+			}
+			player.move(move)
+		} else if got := player.opponentMove(); got != move {
+			t.Fatalf("AI played %s, want %s", got, move)
+		}
+	}
+
+	ended := player.gameEnded()
+	if ended.Result != "0-1" || ended.Reason != "Checkmate" {
+		t.Errorf("game ended %s by %q, want 0-1 by checkmate", ended.Result, ended.Reason)
+	}
+
+	player.send(protocol.TypeGuess, protocol.GuessMessage{Guess: game.GuessAI})
+
+	var verdict protocol.VerdictData
+	player.expect(protocol.StateVerdict, &verdict)
+	if !verdict.Correct || !verdict.IsAI || verdict.AIMeta == nil {
+		t.Errorf("verdict = %+v, want a correct AI guess with AI meta", verdict)
+	}
+
+	games := h.archived(1)
+	if len(games) != 1 || len(games[0].Moves) != len(foolsMate) || !games[0].IsAI {
+		t.Errorf("archived %d games, want the AI game with %d moves", len(games), len(foolsMate))
+	}
+	if guess := games[0].White.Guess + games[0].Black.Guess; guess != game.GuessAI {
+		t.Errorf("archived guess %q, want %s", guess, game.GuessAI)
+	}
+}
+
+func TestResignAgainstAI(t *testing.T) {
+	h := newHarness(t, nil, aiOnly)
+	player := h.connect()
+
+	color := player.matched()
+	if color == "black" {
+		player.opponentMove()
+		player.expect(protocol.StateYourTurn, nil)
+	}
+
+	player.send(protocol.TypeResign, nil)
+
+	want := "0-1"
+	if color == "black" {
+		want = "1-0"
+	}
+
+	ended := player.gameEnded()
+	if ended.Result != want || ended.Reason != "resignation" {
+		t.Errorf("game ended %s by %q, want %s by resignation", ended.Result, ended.Reason, want)
+	}
+}
+
+func TestPGNHiddenUntilGuessed(t *testing.T) {
+	h := newHarness(t, nil, humansOnly)
+	white, black := h.pairHumans()
+
+	white.send(protocol.TypeResign, nil)
+	ended := white.expect(protocol.StateGameEnded, nil)
+	black.gameEnded()
+
+	if status := h.pgnStatus(ended.RoomID); status != http.StatusNotFound {
+		t.Errorf("PGN before guessing answered %d, want %d", status, http.StatusNotFound)
+	}
+
+	white.send(protocol.TypeGuess, protocol.GuessMessage{Guess: game.GuessHuman})
+	white.expect(protocol.StateVerdict, nil)
+
+	if status := h.pgnStatus(ended.RoomID); status != http.StatusNotFound {
+		t.Errorf("PGN before black guessed answered %d, want %d", status, http.StatusNotFound)
+	}
+
+	black.send(protocol.TypeGuess, protocol.GuessMessage{Guess: game.GuessAI})
+	black.expect(protocol.StateVerdict, nil)
+	h.archived(1)
+
+	if status := h.pgnStatus(ended.RoomID); status != http.StatusOK {
+		t.Errorf("PGN after guessing answered %d, want %d", status, http.StatusOK)
+	}
+}
+
+func TestFallsBackToAIWithoutOpponent(t *testing.T) {
+	h := newHarness(t, nil, func(cfg *config.Config) {
+		cfg.Matchmaking.AIProbability = 0
+		cfg.Matchmaking.OpponentTimeout = config.Duration{Duration: 500 * time.Millisecond}
+	})
+	player := h.connect()
+
+	if player.matched() == "black" {
+		player.opponentMove()
+		player.expect(protocol.StateYourTurn, nil)
+	}
+
+	player.send(protocol.TypeAbort, nil)
+	if ended := player.gameEnded(); ended.Reason != "aborted" {
+		t.Errorf("game ended by %q, want aborted", ended.Reason)
+	}
+}
+
+func TestOpponentTimeoutOnVirtualTime(t *testing.T) {
+	h := newHarness(t, nil, func(cfg *config.Config) {
+		cfg.Matchmaking.AIProbability = 0
+		cfg.Matchmaking.OpponentTimeout = config.Duration{Duration: 10 * time.Minute}
+	})
+
+	fake := clock.NewFake(time.Now())
+	h.app.Clock = fake
+	h.app.Rand = random.NewSeeded(1)
+
+	player := h.connect()
+
+	// the matchmaking ticker and the opponent timeout
+	fake.BlockUntil(2)
+	fake.Advance(10 * time.Minute)
+
+	player.matched()
+	player.send(protocol.TypeAbort, nil)
+	if ended := player.gameEnded(); ended.Reason != "aborted" {
+		t.Errorf("game ended by %q, want aborted", ended.Reason)
+	}
+}
+
+func TestConcurrentMessagesEndGameOnce(t *testing.T) {
+	h := newHarness(t, nil, func(cfg *config.Config) {
+		humansOnly(cfg)
+		cfg.Game.TickInterval = config.Duration{Duration: time.Millisecond}
+	})
+	white, black := h.pairHumans()
+
+	// both players fire everything at once, the room's event loop decides
+	// which command ends the game
+	for _, move := range []string{"e2e4", "d2d4", "g1f3"} {
+		white.move(move)
+	}
+	white.send(protocol.TypeOfferDraw, nil)
+	white.send(protocol.TypeResign, nil)
+
+	for _, move := range []string{"e7e5", "d7d5", "g8f6"} {
+		black.move(move)
+	}
+	black.send(protocol.TypeOfferDraw, nil)
+	black.send(protocol.TypeAcceptDraw, nil)
+	black.send(protocol.TypeResign, nil)
+
+	var whiteEnded, blackEnded protocol.GameEndedData
+	ended := white.expect(protocol.StateGameEnded, &whiteEnded)
+	black.expect(protocol.StateGameEnded, &blackEnded)
+	if whiteEnded != blackEnded {
+		t.Errorf("white saw %+v, black saw %+v", whiteEnded, blackEnded)
+	}
+
+	// the guess is only answered once everything sent before it was handled
+	for _, player := range []*client{white, black} {
+		player.send(protocol.TypeGuess, protocol.GuessMessage{Guess: game.GuessHuman})
+		if again := player.count(protocol.StateGameEnded, protocol.StateVerdict); again != 0 {
+			t.Errorf("game ended %d more times", again)
+		}
+	}
+
+	games := h.archived(1)
+	if len(games) != 1 || games[0].Reason != whiteEnded.Reason {
+		t.Errorf("archived %d games, want the one ended by %q", len(games), whiteEnded.Reason)
+	}
+	if saves := h.store.savesOf(ended.RoomID); saves != 1 {
+		t.Errorf("game saved %d times, want once", saves)
+	}
+}
+
+func TestResumeWithinGraceWindow(t *testing.T) {
+	h := newHarness(t, nil, humansOnly)
+	white, black := h.pairHumans()
+
+	white.move("e2e4")
+	black.opponentMove()
+	black.move("e7e5")
+	white.opponentMove()
+
+	white.conn.Close()
+	black.expect(protocol.StateOpponentDisconnected, nil)
+
+	resumed := h.resume(white.token)
+
+	var snapshot protocol.SnapshotData
+	resumed.expect(protocol.StateResumed, &snapshot)
+	if snapshot.Color != "white" || snapshot.Turn != "white" {
+		t.Errorf("resumed as %s with %s to move, want white to move as white", snapshot.Color, snapshot.Turn)
+	}
+	if len(snapshot.Moves) != 2 || snapshot.Moves[0] != "e2e4" || snapshot.Moves[1] != "e7e5" {
+		t.Errorf("snapshot moves = %v, want [e2e4 e7e5]", snapshot.Moves)
+	}
+	if want := "rnbqkbnr/pppp1ppp/8/4p3/4P3/8/PPPP1PPP/RNBQKBNR w KQkq e6 0 2"; snapshot.FEN != want {
+		t.Errorf("snapshot FEN = %s, want %s", snapshot.FEN, want)
+	}
+
+	black.expect(protocol.StateOpponentReconnected, nil)
+
+	resumed.move("g1f3")
+	if got := black.opponentMove(); got != "g1f3" {
+		t.Errorf("black saw move %s, want g1f3", got)
+	}
+}
+
+func TestForfeitAfterGraceWindow(t *testing.T) {
+	h := newHarness(t, nil, func(cfg *config.Config) {
+		humansOnly(cfg)
+		cfg.Game.ReconnectGrace = config.Duration{Duration: 100 * time.Millisecond}
+	})
+	white, black := h.pairHumans()
+
+	white.move("e2e4")
+	black.opponentMove()
+
+	white.conn.Close()
+	black.expect(protocol.StateOpponentDisconnected, nil)
+
+	ended := black.gameEnded()
+	if ended.Result != "0-1" || ended.Reason != "abandoned" {
+		t.Errorf("game ended %s by %q, want 0-1 by abandonment", ended.Result, ended.Reason)
+	}
+
+	var rejected protocol.ErrorData
+	h.resume(white.token).expect(protocol.StateError, &rejected)
+	if rejected.Code != "invalid_session" {
+		t.Errorf("resuming the forfeited game failed with %q, want invalid_session", rejected.Code)
+	}
+}
+
+func TestResumeRejectedAfterGameEnded(t *testing.T) {
+	h := newHarness(t, nil, humansOnly)
+	white, black := h.pairHumans()
+
+	players := []*client{white, black}
+	for i, move := range foolsMate {
+		players[i%2].move(move)
+		players[(i+1)%2].opponentMove()
+	}
+	white.gameEnded()
+
+	var rejected protocol.ErrorData
+	h.resume(white.token).expect(protocol.StateError, &rejected)
+	if rejected.Code != "invalid_session" {
+		t.Errorf("resuming the ended game failed with %q, want invalid_session", rejected.Code)
+	}
+}
@@ -0,0 +1,291 @@
+package ws_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/style77/stockfish-or-not/internal"
+	"github.com/style77/stockfish-or-not/internal/config"
+	"github.com/style77/stockfish-or-not/internal/enginetest"
+	"github.com/style77/stockfish-or-not/internal/games"
+	"github.com/style77/stockfish-or-not/internal/protocol"
+	"github.com/style77/stockfish-or-not/internal/random"
+	"github.com/style77/stockfish-or-not/internal/store"
+	"github.com/style77/stockfish-or-not/internal/ws"
+)
+
+// how long a client waits for a message it expects
+const expectTimeout = 10 * time.Second
+
+func TestMain(m *testing.M) {
+	enginetest.RunIfFakeUCI()
+	os.Exit(m.Run())
+}
+
+// harness runs the websocket handler on a test server, with the fake engine
+// playing the AI's moves from script.
+type harness struct {
+	t      *testing.T
+	app    *internal.App
+	store  *countingStore
+	server *httptest.Server
+}
+
+// newHarness starts a server with quick matchmaking and AI moves, configure
+// adjusts the config before the app is created.
+func newHarness(t *testing.T, script []string, configure func(*config.Config)) *harness {
+	t.Helper()
+
+	cfg := config.Default()
+	cfg.Matchmaking.LookingIntervalFrom = config.Duration{}
+	cfg.Matchmaking.LookingIntervalTo = config.Duration{}
+	cfg.AI.EnginePath = enginetest.Path(script...)
+	cfg.AI.PoolSize = 1
+	cfg.AI.MaxDepth = 1
+	cfg.AI.MoveDelayFrom = config.Duration{}
+	cfg.AI.MoveDelayTo = config.Duration{Duration: 10 * time.Millisecond}
+
+	if configure != nil {
+		configure(cfg)
+	}
+
+	engines, err := internal.NewEngineRegistry(cfg.AI, random.Default())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &harness{t: t, store: newCountingStore()}
+	h.app = internal.CreateApp(cfg, h.store, engines)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		ws.HandleConnections(w, r, h.app)
+	})
+	mux.HandleFunc("GET /games/{roomID}/pgn", func(w http.ResponseWriter, r *http.Request) {
+		games.HandlePGN(w, r, h.app)
+	})
+	h.server = httptest.NewServer(mux)
+
+	t.Cleanup(func() {
+		h.server.Close()
+		engines.Close()
+	})
+
+	return h
+}
+
+// archived waits until n games have been archived and returns them, games
+// are archived once the guess phase is over.
+func (h *harness) archived(n int) []*store.GameRecord {
+	h.t.Helper()
+
+	deadline := time.Now().Add(expectTimeout)
+	for {
+		records, err := h.store.List()
+		if err != nil {
+			h.t.Fatal(err)
+		}
+
+		if len(records) >= n || time.Now().After(deadline) {
+			return records
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// pgnStatus is the status the PGN export answers with for the room.
+func (h *harness) pgnStatus(roomID string) int {
+	h.t.Helper()
+
+	resp, err := http.Get(h.server.URL + "/games/" + roomID + "/pgn")
+	if err != nil {
+		h.t.Fatal("fetching PGN:", err)
+	}
+	resp.Body.Close()
+
+	return resp.StatusCode
+}
+
+// countingStore counts how often each game was saved.
+type countingStore struct {
+	*store.MemoryStore
+	saves map[string]int
+	mux   sync.Mutex
+}
+
+func newCountingStore() *countingStore {
+	return &countingStore{MemoryStore: store.NewMemoryStore(), saves: make(map[string]int)}
+}
+
+func (s *countingStore) Save(game *store.GameRecord) error {
+	s.mux.Lock()
+	s.saves[game.RoomID]++
+	s.mux.Unlock()
+
+	return s.MemoryStore.Save(game)
+}
+
+func (s *countingStore) savesOf(roomID string) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.saves[roomID]
+}
+
+// client is a player connected to the harness' server.
+type client struct {
+	t     *testing.T
+	conn  *websocket.Conn
+	token string // session token from the match, resumes the game
+}
+
+type envelope struct {
+	State   protocol.State  `json:"state"`
+	RoomID  string          `json:"roomID"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data"`
+}
+
+func (h *harness) connect() *client {
+	h.t.Helper()
+	return h.dial("")
+}
+
+// resume reconnects to the game of the session token.
+func (h *harness) resume(token string) *client {
+	h.t.Helper()
+	return h.dial("?resume=" + url.QueryEscape(token))
+}
+
+func (h *harness) dial(query string) *client {
+	h.t.Helper()
+
+	addr := "ws" + strings.TrimPrefix(h.server.URL, "http") + "/" + query
+	conn, _, err := websocket.DefaultDialer.Dial(addr, nil)
+	if err != nil {
+		h.t.Fatal("connecting:", err)
+	}
+	h.t.Cleanup(func() { conn.Close() })
+
+	return &client{t: h.t, conn: conn}
+}
+
+func (c *client) send(msgType protocol.InboundType, data interface{}) {
+	c.t.Helper()
+
+	if data == nil {
+		data = struct{}{}
+	}
+
+	err := c.conn.WriteJSON(map[string]interface{}{"v": protocol.Version, "type": msgType, "data": data})
+	if err != nil {
+		c.t.Fatal("sending", msgType+":", err)
+	}
+}
+
+func (c *client) move(move string) {
+	c.t.Helper()
+	c.send(protocol.TypeMove, protocol.MoveMessage{Move: move})
+}
+
+// expect reads messages until one with state arrives, skipping others, and
+// decodes its data into data when that isn't nil.
+func (c *client) expect(state protocol.State, data interface{}) envelope {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(expectTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	for {
+		var env envelope
+		if err := c.conn.ReadJSON(&env); err != nil {
+			c.t.Fatalf("waiting for state %d: %v", state, err)
+		}
+
+		if env.State != state {
+			continue
+		}
+
+		if data != nil {
+			if err := json.Unmarshal(env.Data, data); err != nil {
+				c.t.Fatalf("decoding data of state %d: %v", state, err)
+			}
+		}
+
+		return env
+	}
+}
+
+// count reads messages until one with state until arrives and returns how
+// many messages with state came before it.
+func (c *client) count(state, until protocol.State) int {
+	c.t.Helper()
+
+	c.conn.SetReadDeadline(time.Now().Add(expectTimeout))
+	defer c.conn.SetReadDeadline(time.Time{})
+
+	count := 0
+	for {
+		var env envelope
+		if err := c.conn.ReadJSON(&env); err != nil {
+			c.t.Fatalf("waiting for state %d: %v", until, err)
+		}
+
+		switch env.State {
+		case until:
+			return count
+		case state:
+			count++
+		}
+	}
+}
+
+// matched waits for the match, keeps the session token and returns the
+// client's color.
+func (c *client) matched() string {
+	c.t.Helper()
+
+	var matched protocol.MatchedData
+	c.expect(protocol.StateMatched, &matched)
+	c.token = matched.SessionToken
+	return matched.Color
+}
+
+// opponentMove waits for the opponent's move and returns it.
+func (c *client) opponentMove() string {
+	c.t.Helper()
+
+	var move protocol.OpponentMoveData
+	c.expect(protocol.StateOpponentMove, &move)
+	return move.Move
+}
+
+func (c *client) gameEnded() protocol.GameEndedData {
+	c.t.Helper()
+
+	var ended protocol.GameEndedData
+	c.expect(protocol.StateGameEnded, &ended)
+	return ended
+}
+
+// pairHumans connects two players and waits until they are matched with each
+// other, white is returned first.
+func (h *harness) pairHumans() (white, black *client) {
+	h.t.Helper()
+
+	first, second := h.connect(), h.connect()
+	if first.matched() == "white" {
+		second.matched()
+		return first, second
+	}
+
+	second.matched()
+	return second, first
+}